@@ -0,0 +1,303 @@
+package xamboo
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/plugins"
+	"github.com/webability-go/xamboo/stat"
+	"github.com/webability-go/xamboo/workers"
+)
+
+// AdminPath is the default path of the admin API
+const AdminPath = "/admin"
+
+// secretKeys are the entries of the config replaced by RedactedConfig
+var secretKeys = map[string]bool{"pass": true, "password": true, "token": true, "secret": true}
+
+var clientCAs sync.Map // file of the client CA => *x509.CertPool
+
+// serveAdmin serves the request if it is for the admin API of the settings.
+// It returns true if the request has been served.
+func serveAdmin(w http.ResponseWriter, r *http.Request, settings assets.Admin) bool {
+	if !settings.Enabled {
+		return false
+	}
+	path := AdminPath
+	if settings.Path != "" {
+		// "/" mounts the API at the root of a dedicated listener
+		path = strings.TrimSuffix(settings.Path, "/")
+	}
+	if !strings.HasPrefix(r.URL.Path, path) {
+		return false
+	}
+	route := r.URL.Path[len(path):]
+	if route != "" && route[0] != '/' {
+		return false
+	}
+
+	switch {
+	case settings.Token == "" && settings.ClientCA == "":
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	case settings.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+settings.Token)) == 1:
+	case settings.ClientCA != "" && verifyClientCert(r, settings.ClientCA):
+	default:
+		if settings.Token != "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return true
+	}
+	adminAPI(w, r, strings.Trim(route, "/"))
+	return true
+}
+
+// adminHandler serves the admin API of the listener, and any other request with the handler
+func adminHandler(settings assets.Admin, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveAdmin(w, r, settings) {
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// verifyClientCert verifies the client certificate of the request against the client CA file.
+// The listener only requests the certificate, the verification is done here so each listener and host has its own CA.
+func verifyClientCert(r *http.Request, file string) bool {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	pool, err := clientCAPool(file)
+	if err != nil {
+		logger.GetCoreLogger("errors").Println("Admin API:", err)
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+func clientCAPool(file string) (*x509.CertPool, error) {
+	if pool, ok := clientCAs.Load(file); ok {
+		return pool.(*x509.CertPool), nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("No certificate into the client CA " + file)
+	}
+	clientCAs.Store(file, pool)
+	return pool, nil
+}
+
+// adminRecent are the rates and the tops of the recent requests
+type adminRecent struct {
+	Rates    stat.Rates
+	TopPages []stat.Count
+	TopIPs   []stat.Count
+}
+
+type adminStats struct {
+	Stats  *stat.Stat
+	Recent adminRecent
+	Hosts  map[string]adminRecent
+}
+
+type adminStatus struct {
+	Plugins  []compiler.PluginStatus // libraries and applications loaded by the plugins manager
+	Compiler []compiler.PluginStatus // plugins supervised by the compiler
+	Workers  []workers.ProcessStatus
+	Builds   compiler.BuildStats
+}
+
+// adminAPI serves the route of the admin API
+func adminAPI(w http.ResponseWriter, r *http.Request, route string) {
+	var data interface{}
+	var err error
+	switch route {
+	case "stats", "stats/pages", "stats/blocks":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		if stat.SystemStat == nil {
+			http.Error(w, "Stats not started", http.StatusServiceUnavailable)
+			return
+		}
+		switch route {
+		case "stats":
+			data = getAdminStats(r)
+		case "stats/pages":
+			data = stat.SystemStat.Pages.Get(r.FormValue("host"))
+		default:
+			data = stat.SystemStat.Blocks.Get(r.FormValue("host"))
+		}
+	case "config":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		data, err = RedactedConfig()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case "caches":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		data = assets.GetCaches()
+	case "caches/flush":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		id := r.FormValue("id")
+		flushed := []assets.CacheStatus{}
+		for _, c := range assets.GetCaches() {
+			if id == "" || c.ID == id {
+				c.Cache.Flush()
+				flushed = append(flushed, c)
+			}
+		}
+		if id != "" && len(flushed) == 0 {
+			http.Error(w, "Unknown cache: "+id, http.StatusNotFound)
+			return
+		}
+		logger.GetCoreLogger("sys").Println("Admin API: flushed", len(flushed), "caches", id)
+		data = flushed
+	case "status":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		data = adminStatus{
+			Plugins:  plugins.GetStatus(),
+			Compiler: compiler.GetStatus(),
+			Workers:  workers.GetStatus(),
+			Builds:   compiler.GetBuildStats(),
+		}
+	case "loglevels":
+		if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+			return
+		}
+		if r.Method == http.MethodPost {
+			host, level := r.FormValue("host"), r.FormValue("level")
+			if err = logger.SetLevel(host, level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.GetCoreLogger("sys").Println("Admin API: log level of", "H["+host+"]", "set to", level)
+		}
+		levels := map[string]string{}
+		for id, level := range logger.GetLevels() {
+			levels[id] = level.String()
+		}
+		data = levels
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(data)
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// getAdminStats returns the snapshot of the stats with the rates and the tops of the server and of each host.
+// The requests are included only with the parameter requests=1.
+func getAdminStats(r *http.Request) adminStats {
+	top, _ := strconv.Atoi(r.FormValue("top"))
+	if top <= 0 {
+		top = config.Config.Stats.Top
+	}
+	if top <= 0 {
+		top = stat.DefaultTop
+	}
+	recent := func(rc *stat.Recent) adminRecent {
+		return adminRecent{Rates: rc.Rates(), TopPages: rc.TopPages(top), TopIPs: rc.TopIPs(top)}
+	}
+
+	s := stat.SystemStat.Snapshot()
+	data := adminStats{Stats: s, Recent: recent(s.Recent), Hosts: map[string]adminRecent{}}
+	withRequests := r.FormValue("requests") == "1"
+	if !withRequests {
+		s.Requests = nil
+	}
+	for id, site := range s.SitesStat {
+		data.Hosts[id] = recent(site.Recent)
+		if !withRequests {
+			site.Requests = nil
+		}
+	}
+	return data
+}
+
+// RedactedConfig returns a copy of the config for the admin tools, as generic JSON data, with the secrets (passwords and tokens) replaced by "[redacted]".
+// The loaded config files, plugins and applications of the hosts are not included.
+func RedactedConfig() (interface{}, error) {
+	c := *config.Config
+	c.Hosts = make(config.Hosts, len(config.Config.Hosts))
+	for i, h := range config.Config.Hosts {
+		h.Config = nil
+		h.Plugins = nil
+		h.Applications = nil
+		c.Hosts[i] = h
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err = json.Unmarshal(buf, &data); err != nil {
+		return nil, err
+	}
+	redact(data)
+	return data, nil
+}
+
+func redact(data interface{}) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if s, ok := value.(string); ok && s != "" && secretKeys[strings.ToLower(k)] {
+				v[k] = "[redacted]"
+				continue
+			}
+			redact(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redact(value)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package xamboo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/logger"
+)
+
+func adminRequest(settings assets.Admin, method string, url string, token string) (*httptest.ResponseRecorder, bool) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return w, serveAdmin(w, r, settings)
+}
+
+func TestServeAdmin(t *testing.T) {
+	saved := config.Config
+	config.Config = &config.ConfigDef{
+		Hosts: config.Hosts{{
+			Name:    "developers",
+			Auth:    assets.Auth{Enabled: true, User: "admin", Pass: "mypass"},
+			Metrics: assets.Metrics{Enabled: true, Token: "mytoken"},
+		}},
+	}
+	logger.Levels = map[string]assets.LogLevel{"": assets.LogInfo, "developers": assets.LogInfo}
+	defer func() {
+		config.Config = saved
+		logger.Levels = nil
+	}()
+
+	settings := assets.Admin{Enabled: true, Token: "secret"}
+	if _, served := adminRequest(settings, "GET", "/administration", ""); served {
+		t.Fatal("a page served by the admin API")
+	}
+	if w, _ := adminRequest(assets.Admin{Enabled: true}, "GET", "/admin/config", ""); w.Code != http.StatusForbidden {
+		t.Fatal("admin API without token nor client CA:", w.Code)
+	}
+	if w, _ := adminRequest(settings, "GET", "/admin/config", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatal("admin API with a wrong token:", w.Code)
+	}
+
+	w, _ := adminRequest(settings, "GET", "/admin/config", "secret")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `"user":"admin"`) || strings.Contains(body, "mypass") || strings.Contains(body, "mytoken") {
+		t.Errorf("config: got %d %s", w.Code, body)
+	}
+
+	if w, _ := adminRequest(settings, "GET", "/admin/loglevels?host=developers&level=debug", "secret"); strings.Contains(w.Body.String(), "debug") {
+		t.Error("log level changed with GET")
+	}
+	w, _ = adminRequest(settings, "POST", "/admin/loglevels?host=developers&level=debug", "secret")
+	levels := map[string]string{}
+	if err := json.Unmarshal(w.Body.Bytes(), &levels); err != nil || levels["developers"] != "debug" || levels[""] != "info" {
+		t.Errorf("log levels: got %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := adminRequest(settings, "POST", "/admin/loglevels?host=unknown&level=debug", "secret"); w.Code != http.StatusBadRequest {
+		t.Error("log level of an unknown host:", w.Code)
+	}
+
+	cache := assets.RegisterCache(xcore.NewXCache("admintest", 0, 0))
+	cache.Set("a", 1)
+	if w, _ := adminRequest(settings, "GET", "/admin/caches/flush?id=admintest", "secret"); w.Code != http.StatusMethodNotAllowed {
+		t.Error("cache flushed with GET:", w.Code)
+	}
+	if w, _ := adminRequest(settings, "POST", "/admin/caches/flush?id=admintest", "secret"); w.Code != http.StatusOK || cache.Count() != 0 {
+		t.Errorf("cache flush: got %d, %d items", w.Code, cache.Count())
+	}
+
+	// API at the root of a dedicated listener
+	if w, _ := adminRequest(assets.Admin{Enabled: true, Path: "/", Token: "secret"}, "GET", "/status", "secret"); w.Code != http.StatusOK {
+		t.Error("status at the root:", w.Code)
+	}
+}
+
+// testCertificate creates a certificate signed by the parent (self signed if parent is nil)
+func testCertificate(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerkey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerkey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestAdminClientCertificate(t *testing.T) {
+	ca := testCertificate(t, "admin ca", nil)
+	other := testCertificate(t, "other ca", nil)
+	client := testCertificate(t, "admin", &ca)
+	intruder := testCertificate(t, "intruder", &other)
+
+	dir, err := ioutil.TempDir("", "xamboo-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cafile := filepath.Join(dir, "ca.pem")
+	if err = ioutil.WriteFile(cafile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the listener only requests the certificate, as the https listeners with an admin client CA
+	settings := assets.Admin{Enabled: true, ClientCA: cafile}
+	server := httptest.NewUnstartedServer(adminHandler(settings, http.NotFoundHandler()))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	for _, test := range []struct {
+		name  string
+		certs []tls.Certificate
+		code  int
+	}{
+		{"no certificate", nil, http.StatusUnauthorized},
+		{"certificate of another CA", []tls.Certificate{intruder}, http.StatusUnauthorized},
+		{"certificate of the client CA", []tls.Certificate{client}, http.StatusOK},
+	} {
+		// a new transport for each certificate, so a connection of another certificate is never reused
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = test.certs
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/admin/caches")
+		transport.CloseIdleConnections()
+		if err != nil {
+			t.Fatal(test.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.code {
+			t.Errorf("%s: got %d, want %d", test.name, resp.StatusCode, test.code)
+		}
+	}
+}
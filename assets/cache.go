@@ -0,0 +1,65 @@
+package assets
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/webability-go/xcore/v2"
+)
+
+// cacheCounters are the hits and misses of a registered cache
+type cacheCounters struct {
+	hits   uint64
+	misses uint64
+	cache  *xcore.XCache
+}
+
+var caches sync.Map // *xcore.XCache => *cacheCounters
+
+// CacheStatus is a copy of the counters of a registered cache
+type CacheStatus struct {
+	ID     string
+	Items  int
+	Hits   uint64
+	Misses uint64
+	Cache  *xcore.XCache `json:"-"`
+}
+
+// RegisterCache registers the cache for the metrics and the admin API, and returns it.
+func RegisterCache(cache *xcore.XCache) *xcore.XCache {
+	caches.LoadOrStore(cache, &cacheCounters{cache: cache})
+	return cache
+}
+
+// CacheGet gets the entry of the cache and counts the hit or the miss if the cache is registered.
+// It returns the same values as cache.Get.
+func CacheGet(cache *xcore.XCache, key string) (interface{}, bool) {
+	data, invalid := cache.Get(key)
+	if c, ok := caches.Load(cache); ok {
+		if data != nil {
+			atomic.AddUint64(&c.(*cacheCounters).hits, 1)
+		} else {
+			atomic.AddUint64(&c.(*cacheCounters).misses, 1)
+		}
+	}
+	return data, invalid
+}
+
+// GetCaches returns the counters of all the registered caches, ordered by id
+func GetCaches() []CacheStatus {
+	list := []CacheStatus{}
+	caches.Range(func(key interface{}, value interface{}) bool {
+		c := value.(*cacheCounters)
+		list = append(list, CacheStatus{
+			ID:     c.cache.ID,
+			Items:  c.cache.Count(),
+			Hits:   atomic.LoadUint64(&c.hits),
+			Misses: atomic.LoadUint64(&c.misses),
+			Cache:  c.cache,
+		})
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
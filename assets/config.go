@@ -53,13 +53,58 @@ type UserAgent struct {
 }
 
 type Log struct {
+	Enabled   bool     `json:"enabled"`
+	Pages     string   `json:"pages"`
+	Errors    string   `json:"errors"`
+	Sys       string   `json:"sys"`
+	Stats     string   `json:"stats"`
+	Rotate    string   `json:"rotate"`
+	Compress  bool     `json:"compress"`
+	Retention int      `json:"retention"`
+	Format    string   `json:"format"`
+	Fields    []string `json:"fields"`
+	Level     string   `json:"level"`
+}
+
+type Compiler struct {
+	Workers   int `json:"workers"`
+	Interval  int `json:"interval"`
+	Retention int `json:"retention"`
+}
+
+type Warmup struct {
+	Enabled  bool `json:"enabled"`
+	FailFast bool `json:"failfast"`
+}
+
+type Watcher struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Metrics is the metrics endpoint of a listener or a host, in Prometheus text format.
+// If Token is set, the endpoint needs an "Authorization: Bearer [token]" header.
+type Metrics struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	Token   string `json:"token"`
+}
+
+// Admin is the admin API of a listener or a host.
+// The API needs an "Authorization: Bearer [token]" header with the Token, or a client certificate signed by the ClientCA (a PEM file) on an https listener.
+// Without Token nor ClientCA, the API refuses all the requests.
+type Admin struct {
 	Enabled  bool   `json:"enabled"`
-	Pages    string `json:"pages"`
-	Errors   string `json:"errors"`
-	Sys      string `json:"sys"`
-	Stats    string `json:"stats"`
-	Rotate   string `json:"rotate"`
-	Compress bool   `json:"compress"`
+	Path     string `json:"path"`
+	Token    string `json:"token"`
+	ClientCA string `json:"clientca"`
+}
+
+// Stats are the settings of the recent requests of the stats: the window in seconds (120 by default), the capacity in requests (10000 by default)
+// and the size of the tops of pages and IPs (10 by default)
+type Stats struct {
+	Window   int `json:"window"`
+	Capacity int `json:"capacity"`
+	Top      int `json:"top"`
 }
 
 type Host struct {
@@ -77,6 +122,11 @@ type Host struct {
 	GZip         GZip       `json:"gzip"`
 	Browser      Browser    `json:"browser"`
 	Log          Log        `json:"log"`
+	Warmup       Warmup     `json:"warmup"`
+	Watcher      Watcher    `json:"watcher"`
+	Metrics      Metrics    `json:"metrics"`
+	Admin        Admin      `json:"admin"`
+	Debug        bool       `json:"debug"`
 	Config       *xconfig.XConfig
 	Plugins      map[string]*plugin.Plugin
 	Applications map[string]Application
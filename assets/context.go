@@ -1,6 +1,7 @@
 package assets
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"plugin"
@@ -10,6 +11,8 @@ import (
 
 // Context structure is needed to be transported between every call from the engine to the different page engines
 type Context struct {
+	Ctx                 context.Context           // The context of the page, derived from the request: canceled when the client is gone or when the timeout of the page is reached
+	RequestID           string                    // The id of the request, given by the client (X-Request-ID header) or generated. It is returned into the X-Request-ID header
 	Request             *http.Request             // The request (and all its data available: headers, variables, form, files, etc)
 	Writer              http.ResponseWriter       // The request (and all its data available: headers, variables, form, files, etc)
 	IsMainPage          bool                      // true it this page is the main page itself, false if any other page or blocks
@@ -23,6 +26,7 @@ type Context struct {
 	LocalPageUsed       string                    // The local real page to use (valid page found)
 	LocalURLparams      []string                  // The local URL params based on local page, if any
 	LoggerError         *log.Logger               // The logger to log errors
+	Logger              *LevelLogger              // The leveled logger of the page, tagged with the host, the page and the request id (level of the host or loglevel of the .page)
 	Sysparams           *xconfig.XConfig          // mandatory, site system params
 	Sessionparams       *xconfig.XConfig          // Optional, for the programer to add any session data he needs.
 	MainPageparams      *xconfig.XConfig          // Original page params (real original .page file)
@@ -19,3 +19,9 @@ type EngineInstance interface {
 	NeedTemplate() bool
 	Run(ctx *Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{}
 }
+
+// EngineInstanceLoader may be implemented by an EngineInstance that is able to load (parse, compile) and cache its data without running the page.
+// It is used by the warm up of the hosts to fill the caches before the listeners are opened.
+type EngineInstanceLoader interface {
+	Load(ctx *Context) error
+}
@@ -0,0 +1,133 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel is the level of a log entry. Only the entries of a level greater or equal to the level of the logger are written.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LogDebug || l > LogError {
+		return "unknown"
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel returns the level of its name: debug, info, warn or error. An empty name is info.
+func ParseLogLevel(name string) (LogLevel, error) {
+	if name == "" {
+		return LogInfo, nil
+	}
+	for i, n := range logLevelNames {
+		if strings.EqualFold(name, n) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogInfo, errors.New("Unknown log level: " + name)
+}
+
+// LevelLogger is a leveled logger: the debug and info entries are written into the Output logger, the warn and error entries into the ErrorOutput logger.
+// Each entry is tagged with the level and the Tags of the logger (for instance host, page and request id).
+// A nil LevelLogger can be used and writes nothing.
+type LevelLogger struct {
+	Level       LogLevel
+	Output      *log.Logger
+	ErrorOutput *log.Logger
+	Tags        string
+}
+
+// With returns a copy of the logger with more tags
+func (l *LevelLogger) With(tags string) *LevelLogger {
+	if l == nil {
+		return nil
+	}
+	n := *l
+	if n.Tags != "" {
+		n.Tags += " "
+	}
+	n.Tags += tags
+	return &n
+}
+
+// WithLevel returns a copy of the logger with another level
+func (l *LevelLogger) WithLevel(level LogLevel) *LevelLogger {
+	if l == nil {
+		return nil
+	}
+	n := *l
+	n.Level = level
+	return &n
+}
+
+// Enabled returns true if the entries of the level are written
+func (l *LevelLogger) Enabled(level LogLevel) bool {
+	return l != nil && level >= l.Level
+}
+
+func (l *LevelLogger) Debug(v ...interface{}) {
+	l.output(LogDebug, fmt.Sprintln(v...))
+}
+
+func (l *LevelLogger) Debugf(format string, v ...interface{}) {
+	l.output(LogDebug, fmt.Sprintf(format, v...))
+}
+
+func (l *LevelLogger) Info(v ...interface{}) {
+	l.output(LogInfo, fmt.Sprintln(v...))
+}
+
+func (l *LevelLogger) Infof(format string, v ...interface{}) {
+	l.output(LogInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *LevelLogger) Warn(v ...interface{}) {
+	l.output(LogWarn, fmt.Sprintln(v...))
+}
+
+func (l *LevelLogger) Warnf(format string, v ...interface{}) {
+	l.output(LogWarn, fmt.Sprintf(format, v...))
+}
+
+func (l *LevelLogger) Error(v ...interface{}) {
+	l.output(LogError, fmt.Sprintln(v...))
+}
+
+func (l *LevelLogger) Errorf(format string, v ...interface{}) {
+	l.output(LogError, fmt.Sprintf(format, v...))
+}
+
+// Println writes an info entry, as a *log.Logger would
+func (l *LevelLogger) Println(v ...interface{}) {
+	l.output(LogInfo, fmt.Sprintln(v...))
+}
+
+func (l *LevelLogger) output(level LogLevel, message string) {
+	if !l.Enabled(level) {
+		return
+	}
+	out := l.Output
+	if level >= LogWarn {
+		out = l.ErrorOutput
+	}
+	if out == nil {
+		return
+	}
+	prefix := strings.ToUpper(level.String())
+	if l.Tags != "" {
+		prefix += " " + l.Tags
+	}
+	out.Output(3, prefix+": "+message)
+}
@@ -2,21 +2,52 @@ package assets
 
 import (
 	"plugin"
+	"sync"
+	"time"
 
 	"github.com/webability-go/xcore/v2"
 	//	"github.com/webability-go/xmodules/context"
 )
 
 type Plugin struct {
-	SourcePath  string
-	PluginPath  string
-	PluginVPath string
-	Version     int
-	Messages    string
-	Status      int // 0: not loaded/compile, 1: OK, 2: compile error (see messages)
-	Lib         *plugin.Plugin
-	Libs        map[string]*plugin.Plugin
+	SourcePath    string
+	PluginPath    string
+	PluginVPath   string
+	Version       int
+	Messages      string
+	Status        int // 0: not loaded/compile, 1: OK, 2: compile error (see messages)
+	LastBuild     time.Time
+	BuildDuration time.Duration
+	LastError     error  // error of the last compilation, nil if it compiled
+	Build         *Build // settings of the compiler, nil for the default go build
+	Lib           *plugin.Plugin
+	Libs          map[string]*plugin.Plugin
 
 	// standard libraries function (can be nil if not a page library)
 	Run func(*Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{}
+
+	// the plugin is shared between the requests and the compiler supervisor
+	mutex sync.Mutex
+}
+
+// Build are the settings of the GO compiler to build the plugins of a host
+type Build struct {
+	GOFLAGS     string        // GOFLAGS environment variable of the build
+	Tags        string        // comma separated list of build tags
+	TrimPath    bool          // build with -trimpath
+	ModuleRoot  string        // directory of the go.mod of the plugins, the build runs into it
+	GOCACHE     string        // GOCACHE directory of the build
+	Timeout     time.Duration // max duration of a build, 0 = no limit
+	Preflight   bool          // verify the GO version and the modules versions against the server before building
+	Interpreted bool          // the library pages are interpreted from the source and not compiled (development mode)
+	Process     bool          // the library pages are compiled as standalone binaries and run by worker processes
+}
+
+// Lock must be called before reading or modifying the plugin
+func (p *Plugin) Lock() {
+	p.mutex.Lock()
+}
+
+func (p *Plugin) Unlock() {
+	p.mutex.Unlock()
 }
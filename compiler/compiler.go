@@ -5,49 +5,199 @@
 package compiler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/webability-go/xamboo/assets"
-	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/config"
 )
 
 type Worker struct {
 	ready       chan bool
+	err         error
 	Subscribers []chan bool
 }
 
+// Compile builds the next version of the plugin. The lock of the plugin is only taken to read the version and to write the result,
+// so the status of the plugin can be read during the compilation.
 func (w *Worker) Compile(ctx *assets.Context, plugin *assets.Plugin) {
 
-	messages := "Recompiling: " + plugin.SourcePath + "\n"
+	// Change version +1, only once compiled: the previous version is kept if the compilation fails
+	plugin.Lock()
+	version := plugin.Version + 1
+	plugin.Unlock()
+	target := plugin.PluginPath + "." + fmt.Sprint(version)
 
-	// Change version +1
-	plugin.Version++
-	plugin.PluginVPath = plugin.PluginPath + "." + fmt.Sprint(plugin.Version)
+	start := time.Now()
+	messages, err := build(plugin.SourcePath, target, plugin.Build)
+	duration := time.Since(start)
+	recordBuild(duration, err)
 
-	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", plugin.PluginVPath, plugin.SourcePath)
-	//	cmd.Stdout = ctx.LoggerError.Writer()
-	//	cmd.Stderr = ctx.LoggerError.Writer()
-	//	err := cmd.Run()
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		messages += "Error running go build:\n" + fmt.Sprint(err)
+	plugin.Lock()
+	plugin.LastBuild = start
+	plugin.BuildDuration = duration
+	if err == nil {
+		plugin.Version = version
+		plugin.PluginVPath = target
+		for _, file := range removeOldVersions(plugin.PluginPath) {
+			messages += "Old version removed: " + file + "\n"
+		}
+	} else {
+		w.err = err
 	}
-	messages += string(out)
 
 	plugin.Messages += messages
+	plugin.Unlock()
 	ctx.LoggerError.Println(messages)
+	// The subscribers are notified by the creator of the worker, under the mutex of the pile
 	w.ready <- true
-	w.Broadcast()
 }
 
+// workerMain is the generated main of the worker binaries
+const workerMain = "xamboo_worker_main.go"
+
+// workerSources copies the source of the page into a temporary directory with the generated main of a worker, and returns the directory
+func workerSources(source string) (string, error) {
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", "xamboo-worker-")
+	if err != nil {
+		return "", err
+	}
+	main := `// Code generated by the xamboo compiler. DO NOT EDIT.
+
+package main
+
+import "github.com/webability-go/xamboo/workers"
+
+func main() {
+	workers.Serve(Run)
+}
+`
+	err = ioutil.WriteFile(filepath.Join(dir, filepath.Base(source)), data, 0644)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, workerMain), []byte(main), 0644)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// build compiles the source into the plugin file (or the worker binary) with the settings of the host, and returns the messages of the compiler.
+// It does not touch the Plugin, the caller is in charge to update it with the result.
+// If the compilation fails, the error is a *Error with the diagnostics of the compiler.
+func build(source string, target string, settings *assets.Build) (string, error) {
+
+	messages := "Recompiling: " + source + "\n"
+	if settings == nil {
+		settings = &assets.Build{}
+	}
+
+	// the build may run into the module root, so the paths must be absolute
+	if settings.ModuleRoot != "" {
+		source, _ = filepath.Abs(source)
+		target, _ = filepath.Abs(target)
+	}
+	env := os.Environ()
+	if settings.GOFLAGS != "" {
+		env = append(env, "GOFLAGS="+settings.GOFLAGS)
+	}
+	if settings.GOCACHE != "" {
+		gocache, _ := filepath.Abs(settings.GOCACHE)
+		env = append(env, "GOCACHE="+gocache)
+	}
+
+	ctx := context.Background()
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+
+	if settings.Preflight {
+		cmd := exec.CommandContext(ctx, "go", "env", "GOVERSION")
+		cmd.Dir = settings.ModuleRoot
+		cmd.Env = env
+		mismatches := preflight(cmd, settings.ModuleRoot, source)
+		if len(mismatches) > 0 {
+			out := strings.Join(mismatches, "\n") + "\n"
+			messages += "Error in the preflight check:\n" + out
+			return messages, &Error{
+				SourcePath: source,
+				Output:     out,
+				Err:        errors.New("the plugin cannot be loaded by this server"),
+			}
+		}
+	}
+
+	args := []string{"build"}
+	sources := []string{source}
+	if settings.Process {
+		// standalone binary of a worker: the page is built with a generated main into a temporary directory
+		dir, err := workerSources(source)
+		if err != nil {
+			messages += "Error preparing the worker sources:\n" + err.Error() + "\n"
+			return messages, &Error{SourcePath: source, Err: err}
+		}
+		defer os.RemoveAll(dir)
+		sources = []string{filepath.Join(dir, filepath.Base(source)), filepath.Join(dir, workerMain)}
+	} else {
+		args = append(args, "-buildmode=plugin")
+	}
+	if settings.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	if settings.Tags != "" {
+		args = append(args, "-tags", settings.Tags)
+	}
+	args = append(args, "-o", target)
+	args = append(args, sources...)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = settings.ModuleRoot
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if settings.Process {
+		// the diagnostics refer to the original source
+		out = []byte(strings.ReplaceAll(string(out), sources[0], source))
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("go build timed out after %s", settings.Timeout)
+		}
+		messages += "Error running go build:\n" + fmt.Sprint(err)
+		messages += string(out)
+		return messages, &Error{
+			SourcePath:  source,
+			Output:      string(out),
+			Diagnostics: parseDiagnostics(string(out)),
+			Err:         err,
+		}
+	}
+	messages += string(out)
+	return messages, nil
+}
+
+// Subscribe must be called with the mutex of the pile locked
 func (w *Worker) Subscribe() chan bool {
 	c := make(chan bool)
 	w.Subscribers = append(w.Subscribers, c)
 	return c
 }
 
+// Broadcast must be called with the mutex of the pile locked
 func (w *Worker) Broadcast() {
 	for _, c := range w.Subscribers {
 		c <- true
@@ -72,6 +222,8 @@ func (p *Pile) createCompiler(ctx *assets.Context, plugin *assets.Plugin) *Worke
 	return w
 }
 
+// PleaseCompile compiles the plugin, or waits for the compilation if it is already compiling.
+// It returns a *Error with the compiler output and diagnostics if the compilation failed.
 func (p *Pile) PleaseCompile(ctx *assets.Context, plugin *assets.Plugin) error {
 
 	p.mutex.Lock()
@@ -82,78 +234,27 @@ func (p *Pile) PleaseCompile(ctx *assets.Context, plugin *assets.Plugin) error {
 		<-readychannel
 	} else {
 		// 1. Creates a channel, send message to supervisor, wait for response
-		worker := p.createCompiler(ctx, plugin)
+		worker = p.createCompiler(ctx, plugin)
 		p.mutex.Unlock()
 		<-worker.ready
 		// destroys the Worker once ready
 		p.mutex.Lock()
-		worker.Broadcast()
-		// clean suscribers just in case some new between the ready and now
+		// no new suscribers can come once the worker is out of the pile
 		delete(p.Workers, plugin.SourcePath)
+		worker.Broadcast()
 		p.mutex.Unlock()
 	}
-	return nil
+	return worker.err
 }
 
+// PleaseCompile compiles the plugin with the pile of compilers. The caller must not own the lock of the plugin, it is taken to write the result.
 func PleaseCompile(ctx *assets.Context, plugin *assets.Plugin) error {
 	return CPile.PleaseCompile(ctx, plugin)
 }
 
-/*
-func OldPleaseCompile(path string, plugin string, version int, logger *log.Logger) (int, error) {
-
-	newversion := 0
-	CPile.mutex.RLock()
-	if worker, ok := CPile.Workers[path]; ok {
-		CPile.mutex.RUnlock()
-		newversion = worker.version
-		readychannel := worker.Subscribe()
-		<-readychannel
-	} else {
-		// 1. Creates a channel, send message to supervisor, wait for response
-		CPile.mutex.RUnlock()
-		newversion = searchNextFreeVersion(plugin, version)
-		worker := CPile.CreateCompiler(path, plugin, newversion, logger)
-		<-worker.ready
-		// destroys the Worker
-		CPile.mutex.Lock()
-		delete(CPile.Workers, path)
-		CPile.mutex.Unlock()
-	}
-	return newversion, nil
-}
-
-func searchNextFreeVersion(plugin string, version int) int {
-
-	// search if version, version+1, version+2.... exists, return next available version
-	var newversion int
-	var newfile string
-	for newversion = version; true; newversion++ {
-		if newversion > 0 {
-			newfile = plugin + fmt.Sprintf(".%d", newversion)
-		} else {
-			newfile = plugin
-		}
-		if !utils.FileExists(newfile) {
-			break
-		}
-	}
-	return newversion
-}
-*/
-func Supervisor() {
-
-	CPile.Workers = make(map[string]*Worker)
-
-	slogger := logger.GetCoreLogger("sys")
-	slogger.Println("Launching the compilation supervisor.")
-
-	// put order in any .go and .so.xx,
-
-	// listen to the things to compile and recompile
-}
-
 func Start() {
+	// The pile must exist before any page asks for a compilation (warm up of the hosts starts right after)
+	CPile.Workers = make(map[string]*Worker)
 	// Supervisor will work until the xamboo is working.
-	go Supervisor()
+	go Supervisor(config.Config.Compiler)
 }
@@ -0,0 +1,65 @@
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is one error reported by the go compiler on a file and line of the source
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+// Error is the error returned by PleaseCompile when the compilation of a plugin failed.
+// It contains the full output of the compiler and the diagnostics parsed from it.
+type Error struct {
+	SourcePath  string
+	Output      string
+	Diagnostics []Diagnostic
+	Err         error // the error of the go build command itself
+}
+
+func (e *Error) Error() string {
+	return "Error: the GO code could not compile " + e.SourcePath + "\n" + e.Output
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var diagnosticline = regexp.MustCompile(`^(.+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// parseDiagnostics extracts the file:line:column: message lines from the output of go build.
+// The lines starting with a tab are the continuation of the previous message.
+func parseDiagnostics(output string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "\t") && len(diagnostics) > 0 {
+			diagnostics[len(diagnostics)-1].Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		m := diagnosticline.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{File: m[1], Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			d.Column, _ = strconv.Atoi(m[3])
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
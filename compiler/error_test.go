@@ -0,0 +1,28 @@
+package compiler
+
+import (
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `# command-line-arguments
+pages/home/home.go:12:2: undefined: fmt.Printx
+pages/home/home.go:20:9: cannot use x (variable of type int) as string value in return statement
+	have (int)
+	want (string)
+pages/home/home.go:31: syntax error: unexpected newline
+`
+	diagnostics := parseDiagnostics(output)
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if d := diagnostics[0]; d.File != "pages/home/home.go" || d.Line != 12 || d.Column != 2 || d.Message != "undefined: fmt.Printx" {
+		t.Errorf("Wrong first diagnostic: %#v", d)
+	}
+	if d := diagnostics[1]; d.Message != "cannot use x (variable of type int) as string value in return statement\nhave (int)\nwant (string)" {
+		t.Errorf("Wrong continuation lines: %q", d.Message)
+	}
+	if d := diagnostics[2]; d.Line != 31 || d.Column != 0 || d.String() != "pages/home/home.go:31: syntax error: unexpected newline" {
+		t.Errorf("Wrong diagnostic without column: %#v", d)
+	}
+}
@@ -0,0 +1,157 @@
+package compiler
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// A plugin can only be loaded by the server if it was built with the same GO version and the same versions of the shared modules.
+// If not, plugin.Open fails with a message like "plugin was built with a different version of package ...".
+// The preflight check compares them before building, so the error is clear.
+
+// findGoMod returns the go.mod of the module root, or the first go.mod found into the directory of the source and its parents
+func findGoMod(moduleroot string, source string) string {
+	dir := moduleroot
+	if dir == "" {
+		dir = source
+		if fi, err := os.Stat(source); err != nil || !fi.IsDir() {
+			dir = filepath.Dir(source)
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		file := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		if moduleroot != "" {
+			// the module root must contain the go.mod itself
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseGoMod returns the required modules of the go.mod file, with their versions.
+// The replaced modules are returned with the version of the replacement, or "=> [path]" for a local replacement.
+func parseGoMod(data string) map[string]string {
+	modules := map[string]string{}
+	replaces := map[string]string{}
+	block := ""
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		switch fields[0] {
+		case "require":
+			if len(fields) >= 3 {
+				modules[fields[1]] = fields[2]
+			}
+		case "replace":
+			// replace old [version] => new [version]
+			for i, f := range fields {
+				if f == "=>" && i+1 < len(fields) {
+					version := "=> " + fields[i+1]
+					if i+2 < len(fields) {
+						version = fields[i+2]
+					}
+					replaces[fields[1]] = version
+				}
+			}
+		}
+	}
+	for path, version := range replaces {
+		if _, ok := modules[path]; ok {
+			modules[path] = version
+		}
+	}
+	return modules
+}
+
+// serverModules returns the modules the server was built with, with their versions
+func serverModules() map[string]string {
+	modules := map[string]string{}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modules
+	}
+	for _, dep := range info.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+			if version == "" {
+				version = "=> " + dep.Replace.Path
+			}
+		}
+		modules[dep.Path] = version
+	}
+	return modules
+}
+
+// compareModules returns the mismatches between the modules of the server and the modules required by the plugin.
+// Local replacements cannot be compared and are ignored.
+func compareModules(server map[string]string, plugin map[string]string) []string {
+	mismatches := []string{}
+	for path, version := range plugin {
+		sversion, ok := server[path]
+		if !ok || sversion == version {
+			continue
+		}
+		if strings.HasPrefix(version, "=> ") || strings.HasPrefix(sversion, "=> ") {
+			continue
+		}
+		mismatches = append(mismatches, "Module "+path+": the server uses "+sversion+", the plugin requires "+version)
+	}
+	return mismatches
+}
+
+// preflight verifies the GO version of the toolchain and the versions of the modules of the plugin against the server.
+// It returns the list of mismatches, empty if the plugin can be loaded by the server.
+func preflight(cmd *exec.Cmd, moduleroot string, source string) []string {
+	mismatches := []string{}
+	if out, err := cmd.Output(); err == nil {
+		goversion := strings.TrimSpace(string(out))
+		if goversion != runtime.Version() {
+			mismatches = append(mismatches, "GO version: the server is built with "+runtime.Version()+", the compiler is "+goversion)
+		}
+	}
+	gomod := findGoMod(moduleroot, source)
+	if gomod == "" {
+		return mismatches
+	}
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return append(mismatches, err.Error())
+	}
+	mismatches = append(mismatches, compareModules(serverModules(), parseGoMod(string(data)))...)
+	return mismatches
+}
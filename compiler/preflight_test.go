@@ -0,0 +1,39 @@
+package compiler
+
+import (
+	"testing"
+)
+
+func TestPreflightModules(t *testing.T) {
+	gomod := `module example.com/pages
+
+go 1.14
+
+require github.com/webability-go/xcore/v2 v2.0.4 // indirect
+
+require (
+	github.com/webability-go/xconfig v0.4.1
+	github.com/webability-go/xdommask v0.0.3
+	github.com/webability-go/xamboo v1.5.0
+)
+
+replace github.com/webability-go/xamboo => ../xamboo
+`
+	modules := parseGoMod(gomod)
+	if len(modules) != 4 || modules["github.com/webability-go/xcore/v2"] != "v2.0.4" || modules["github.com/webability-go/xconfig"] != "v0.4.1" {
+		t.Fatalf("Wrong modules: %v", modules)
+	}
+	if modules["github.com/webability-go/xamboo"] != "=> ../xamboo" {
+		t.Errorf("Wrong replaced module: %q", modules["github.com/webability-go/xamboo"])
+	}
+
+	server := map[string]string{
+		"github.com/webability-go/xcore/v2": "v2.0.4",
+		"github.com/webability-go/xconfig":  "v0.4.2",
+		"github.com/webability-go/xamboo":   "v1.4.1",
+	}
+	mismatches := compareModules(server, modules)
+	if len(mismatches) != 1 || mismatches[0] != "Module github.com/webability-go/xconfig: the server uses v0.4.2, the plugin requires v0.4.1" {
+		t.Errorf("Wrong mismatches: %v", mismatches)
+	}
+}
@@ -0,0 +1,44 @@
+package compiler
+
+import (
+	"time"
+
+	"github.com/webability-go/xconfig"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// BuildSettings reads the compiler settings of a host from its config file:
+//
+//	compiler.goflags=-mod=mod
+//	compiler.tags=prod,json1
+//	compiler.trimpath=yes
+//	compiler.moduleroot=./
+//	compiler.gocache=./cache/go-build
+//	compiler.timeout=120
+//	compiler.preflight=yes
+//	compiler.interpreted=no
+//	compiler.process=no
+//
+// The timeout is in seconds. It returns nil if there are no settings (default go build into the working directory of the server).
+func BuildSettings(config *xconfig.XConfig) *assets.Build {
+	if config == nil {
+		return nil
+	}
+	c := config.GetConfig("compiler")
+	if c == nil {
+		return nil
+	}
+	settings := &assets.Build{}
+	settings.GOFLAGS, _ = c.GetString("goflags")
+	settings.Tags, _ = c.GetString("tags")
+	settings.TrimPath, _ = c.GetBool("trimpath")
+	settings.ModuleRoot, _ = c.GetString("moduleroot")
+	settings.GOCACHE, _ = c.GetString("gocache")
+	timeout, _ := c.GetInt("timeout")
+	settings.Timeout = time.Duration(timeout) * time.Second
+	settings.Preflight, _ = c.GetBool("preflight")
+	settings.Interpreted, _ = c.GetBool("interpreted")
+	settings.Process, _ = c.GetBool("process")
+	return settings
+}
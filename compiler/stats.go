@@ -0,0 +1,33 @@
+package compiler
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// the counters of the builds, for the metrics
+var builds, failures, buildnanos uint64
+
+// BuildStats are the counters of the builds since the start of the server
+type BuildStats struct {
+	Builds   uint64
+	Failures uint64
+	Duration time.Duration // total duration of the builds
+}
+
+func recordBuild(duration time.Duration, err error) {
+	atomic.AddUint64(&builds, 1)
+	atomic.AddUint64(&buildnanos, uint64(duration))
+	if err != nil {
+		atomic.AddUint64(&failures, 1)
+	}
+}
+
+// GetBuildStats returns the counters of the builds
+func GetBuildStats() BuildStats {
+	return BuildStats{
+		Builds:   atomic.LoadUint64(&builds),
+		Failures: atomic.LoadUint64(&failures),
+		Duration: time.Duration(atomic.LoadUint64(&buildnanos)),
+	}
+}
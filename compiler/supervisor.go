@@ -0,0 +1,244 @@
+package compiler
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/logger"
+)
+
+// The supervisor keeps track of all the plugins known by the engines.
+// It verifies the sources of the loaded plugins and recompiles them in background when they change,
+// with a limited number of compilations at the same time. The previous version of the plugin keeps serving
+// the pages until the new version is compiled, then the engine loads it on the next hit.
+
+type entry struct {
+	plugin    *assets.Plugin
+	logger    *log.Logger
+	queued    bool
+	compiling bool
+	failed    time.Time // modification time of the source that failed to compile in background, to not retry it again and again (protected by the plugin lock)
+}
+
+type supervisor struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+	queue   chan *entry
+}
+
+var sup = &supervisor{
+	entries: map[string]*entry{},
+}
+
+// PluginStatus is a copy of the status of a plugin known by the supervisor
+type PluginStatus struct {
+	SourcePath    string
+	PluginPath    string
+	Version       int
+	Status        int
+	Queued        bool
+	Compiling     bool
+	LastBuild     time.Time
+	BuildDuration time.Duration
+	Messages      string
+	Diagnostics   []Diagnostic // diagnostics of the last compilation if it failed
+}
+
+// Register adds the plugin to the supervisor, so it will be recompiled in background when its source changes.
+// The logger receives the compiler messages.
+func Register(plugin *assets.Plugin, logger *log.Logger) {
+	sup.mutex.Lock()
+	sup.entries[plugin.SourcePath] = &entry{plugin: plugin, logger: logger}
+	sup.mutex.Unlock()
+}
+
+// Notify tells the supervisor the file has changed (for instance from a file system watcher).
+// If the file is the source of a registered plugin, its compilation is queued.
+func Notify(path string) {
+	sup.mutex.Lock()
+	e, ok := sup.entries[path]
+	sup.mutex.Unlock()
+	if ok {
+		sup.enqueue(e)
+	}
+}
+
+// GetStatus returns the status of all the plugins known by the supervisor, ordered by source path
+func GetStatus() []PluginStatus {
+	sup.mutex.Lock()
+	list := make([]PluginStatus, 0, len(sup.entries))
+	for _, e := range sup.entries {
+		e.plugin.Lock()
+		var diagnostics []Diagnostic
+		if cerr, ok := e.plugin.LastError.(*Error); ok {
+			diagnostics = cerr.Diagnostics
+		}
+		list = append(list, PluginStatus{
+			SourcePath:    e.plugin.SourcePath,
+			PluginPath:    e.plugin.PluginVPath,
+			Version:       e.plugin.Version,
+			Status:        e.plugin.Status,
+			Queued:        e.queued,
+			Compiling:     e.compiling,
+			LastBuild:     e.plugin.LastBuild,
+			BuildDuration: e.plugin.BuildDuration,
+			Messages:      e.plugin.Messages,
+			Diagnostics:   diagnostics,
+		})
+		e.plugin.Unlock()
+	}
+	sup.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].SourcePath < list[j].SourcePath })
+	return list
+}
+
+func (s *supervisor) enqueue(e *entry) {
+	s.mutex.Lock()
+	if e.queued || e.compiling || s.queue == nil {
+		s.mutex.Unlock()
+		return
+	}
+	e.queued = true
+	s.mutex.Unlock()
+	select {
+	case s.queue <- e:
+	default:
+		// the queue is full, the caller (the watcher) must not wait: the periodic verification of the supervisor will find the change
+		s.mutex.Lock()
+		e.queued = false
+		s.mutex.Unlock()
+	}
+}
+
+// mustRebuild returns true if the source of a loaded (or failed) plugin is newer than its compiled version
+func (e *entry) mustRebuild() bool {
+	fi, err := os.Stat(e.plugin.SourcePath)
+	if err != nil {
+		return false
+	}
+	mtime := fi.ModTime()
+
+	e.plugin.Lock()
+	status := e.plugin.Status
+	vpath := e.plugin.PluginVPath
+	lastbuild := e.plugin.LastBuild
+	failed := e.failed
+	e.plugin.Unlock()
+
+	if mtime.Equal(failed) {
+		return false
+	}
+
+	switch status {
+	case 1:
+		fp, err := os.Stat(vpath)
+		return err != nil || mtime.After(fp.ModTime())
+	case 2:
+		return mtime.After(lastbuild)
+	}
+	// status 0: the engine will compile and load it on the next hit
+	return false
+}
+
+func (s *supervisor) worker() {
+	for e := range s.queue {
+		s.mutex.Lock()
+		e.queued = false
+		e.compiling = true
+		s.mutex.Unlock()
+
+		if e.mustRebuild() {
+			s.rebuild(e)
+		}
+
+		s.mutex.Lock()
+		e.compiling = false
+		s.mutex.Unlock()
+	}
+}
+
+// rebuild compiles the next version of the plugin. The plugin is only modified once the compilation is done,
+// so the actual version keeps serving the pages meanwhile.
+func (s *supervisor) rebuild(e *entry) {
+	fi, _ := os.Stat(e.plugin.SourcePath)
+
+	e.plugin.Lock()
+	version := e.plugin.Version + 1
+	e.plugin.Unlock()
+	target := e.plugin.PluginPath + "." + fmt.Sprint(version)
+
+	start := time.Now()
+	messages, err := build(e.plugin.SourcePath, target, e.plugin.Build)
+	duration := time.Since(start)
+	recordBuild(duration, err)
+
+	e.plugin.Lock()
+	if err == nil {
+		for _, file := range removeOldVersions(e.plugin.PluginPath) {
+			messages += "Old version removed: " + file + "\n"
+		}
+	}
+	e.plugin.Messages += messages
+	e.plugin.LastBuild = start
+	e.plugin.BuildDuration = duration
+	e.plugin.LastError = err
+	if err == nil {
+		e.plugin.Version = version
+		e.plugin.PluginVPath = target
+		// the engine will load the new version on the next hit
+		e.plugin.Status = 0
+		e.failed = time.Time{}
+	} else if fi != nil {
+		e.failed = fi.ModTime()
+	}
+	e.plugin.Unlock()
+
+	if e.logger != nil {
+		e.logger.Println(messages)
+	}
+}
+
+// Supervisor launches the compiler workers and verifies the sources of the registered plugins every interval, with the given compiler settings
+func Supervisor(settings assets.Compiler) {
+
+	slogger := logger.GetCoreLogger("sys")
+	slogger.Println("Launching the compilation supervisor.")
+
+	workers := settings.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	interval := time.Duration(settings.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	sup.mutex.Lock()
+	sup.queue = make(chan *entry, 1024)
+	sup.mutex.Unlock()
+	for i := 0; i < workers; i++ {
+		go sup.worker()
+	}
+
+	// listen to the things to compile and recompile
+	for {
+		time.Sleep(interval)
+		sup.mutex.Lock()
+		list := make([]*entry, 0, len(sup.entries))
+		for _, e := range sup.entries {
+			list = append(list, e)
+		}
+		sup.mutex.Unlock()
+		for _, e := range list {
+			if e.mustRebuild() {
+				sup.enqueue(e)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// testSupervisor replaces the supervisor by an empty one with a queue of size entries, until the returned function is called
+func testSupervisor(size int) func() {
+	saved := sup
+	sup = &supervisor{entries: map[string]*entry{}, queue: make(chan *entry, size)}
+	return func() { sup = saved }
+}
+
+// testPlugin creates a source and a compiled version into dir, with the modification times of the source and of the plugin
+func testPlugin(t *testing.T, dir string, source time.Time, compiled time.Time) *assets.Plugin {
+	p := &assets.Plugin{
+		SourcePath:  filepath.Join(dir, "page.go"),
+		PluginPath:  filepath.Join(dir, "page.so"),
+		PluginVPath: filepath.Join(dir, "page.so.1"),
+		Version:     1,
+	}
+	for file, mtime := range map[string]time.Time{p.SourcePath: source, p.PluginVPath: compiled} {
+		if err := ioutil.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestMustRebuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-supervisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	e := &entry{plugin: testPlugin(t, dir, now, old)}
+	for _, test := range []struct {
+		status    int
+		lastbuild time.Time
+		failed    time.Time
+		want      bool
+	}{
+		{0, time.Time{}, time.Time{}, false}, // the engine compiles it on the next hit
+		{1, time.Time{}, time.Time{}, true},  // loaded, the source is newer than the plugin
+		{2, old, time.Time{}, true},          // failed, the source changed since the last build
+		{2, now.Add(time.Minute), time.Time{}, false},
+		{1, time.Time{}, now, false}, // this version of the source already failed in background
+	} {
+		e.plugin.Status = test.status
+		e.plugin.LastBuild = test.lastbuild
+		e.failed = test.failed
+		if got := e.mustRebuild(); got != test.want {
+			t.Errorf("status %d, last build %v, failed %v: got %v, want %v", test.status, test.lastbuild, test.failed, got, test.want)
+		}
+	}
+
+	// the plugin is up to date
+	e = &entry{plugin: testPlugin(t, dir, old, now)}
+	e.plugin.Status = 1
+	if e.mustRebuild() {
+		t.Error("up to date plugin rebuilt")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go compiler is not available")
+	}
+	dir, err := ioutil.TempDir("", "xamboo-supervisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := testPlugin(t, dir, time.Now(), time.Now().Add(-time.Hour))
+	if err := ioutil.WriteFile(p.SourcePath, []byte("package main\n\nfunc Run() string { return \"ok\" }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the versions 1 to 3 exist: the 1 is removed once the version 4 is built
+	p.Version = 3
+	for _, v := range []string{".2", ".3"} {
+		if err := ioutil.WriteFile(p.PluginPath+v, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := &entry{plugin: p}
+	sup.rebuild(e)
+	if p.LastError != nil {
+		t.Fatalf("build failed: %v\n%s", p.LastError, p.Messages)
+	}
+	if p.Version != 4 || p.Status != 0 {
+		t.Errorf("got the version %d and the status %d, want 4 and 0", p.Version, p.Status)
+	}
+	// the removed versions are into the messages of the plugin
+	if !strings.Contains(p.Messages, "Old version removed: "+p.PluginPath+".1\n") {
+		t.Errorf("messages: got %q", p.Messages)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	defer testSupervisor(2)()
+
+	p := &assets.Plugin{SourcePath: "page.go", PluginPath: "page.so", Status: 1}
+	Register(p, log.New(ioutil.Discard, "", 0))
+
+	Notify("other.go")
+	Notify("page.go")
+	Notify("page.go")
+	if len(sup.queue) != 1 {
+		t.Fatalf("queue: got %d entries, want 1", len(sup.queue))
+	}
+	status := GetStatus()
+	if len(status) != 1 || !status[0].Queued || status[0].Compiling || status[0].SourcePath != "page.go" {
+		t.Errorf("status: got %+v", status)
+	}
+
+	// a full queue never blocks the caller, the change is found later by the periodic verification
+	other := &assets.Plugin{SourcePath: "other.go", PluginPath: "other.so", Status: 1}
+	Register(other, nil)
+	sup.queue <- &entry{plugin: &assets.Plugin{}}
+	done := make(chan bool)
+	go func() {
+		Notify("other.go")
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+	for _, s := range GetStatus() {
+		if s.SourcePath == "other.go" && s.Queued {
+			t.Error("plugin marked as queued but not into the queue")
+		}
+	}
+}
+
+func TestPleaseCompileUnlocked(t *testing.T) {
+	defer testSupervisor(1)()
+	saved := CPile.Workers
+	defer func() { CPile.Workers = saved }()
+
+	// a compilation of the plugin is already running: PleaseCompile waits for it
+	p := &assets.Plugin{SourcePath: "page.go", PluginPath: "page.so", Status: 0}
+	Register(p, nil)
+	w := &Worker{ready: make(chan bool), Subscribers: []chan bool{}}
+	CPile.Workers = map[string]*Worker{p.SourcePath: w}
+
+	done := make(chan error)
+	go func() {
+		done <- PleaseCompile(&assets.Context{LoggerError: log.New(ioutil.Discard, "", 0)}, p)
+	}()
+	for subscribed := false; !subscribed; {
+		CPile.mutex.Lock()
+		subscribed = len(w.Subscribers) == 1
+		CPile.mutex.Unlock()
+		runtime.Gosched()
+	}
+
+	// the status of the plugin can be read during the compilation
+	status := make(chan []PluginStatus)
+	go func() { status <- GetStatus() }()
+	select {
+	case s := <-status:
+		if len(s) != 1 || s[0].Status != 0 {
+			t.Errorf("status: got %+v", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetStatus blocked during the compilation")
+	}
+
+	CPile.mutex.Lock()
+	w.Broadcast()
+	CPile.mutex.Unlock()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,99 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/logger"
+)
+
+// Each compilation of a plugin creates a new file [plugin].so.[version], since a plugin already loaded cannot be loaded again.
+// The old versions are removed at start and after each compilation, keeping only the last ones (retention of the compiler config).
+
+// retention returns the number of versions to keep for each plugin
+func retention() int {
+	r := config.Config.Compiler.Retention
+	if r <= 0 {
+		r = 3
+	}
+	return r
+}
+
+// versions returns the existing versions of the plugin on disk, in ascending order
+func versions(pluginpath string) []int {
+	files, err := ioutil.ReadDir(filepath.Dir(pluginpath))
+	if err != nil {
+		return nil
+	}
+	base := filepath.Base(pluginpath) + "."
+	list := []int{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, base) {
+			continue
+		}
+		v, err := strconv.Atoi(name[len(base):])
+		if err != nil || v <= 0 {
+			continue
+		}
+		list = append(list, v)
+	}
+	sort.Ints(list)
+	return list
+}
+
+// LastVersion returns the last compiled version of the plugin on disk, 0 if there is none.
+// The engines use it so the versions continue after a restart of the server, instead of overwriting the existing files.
+func LastVersion(pluginpath string) int {
+	list := versions(pluginpath)
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1]
+}
+
+// removeOldVersions removes the versions of the plugin on disk older than the retention.
+// A loaded plugin stays in memory even if its file is removed.
+func removeOldVersions(pluginpath string) []string {
+	list := versions(pluginpath)
+	removed := []string{}
+	for i := 0; i < len(list)-retention(); i++ {
+		file := pluginpath + "." + strconv.Itoa(list[i])
+		if os.Remove(file) == nil {
+			removed = append(removed, file)
+		}
+	}
+	return removed
+}
+
+// CleanVersions removes the old versions of all the plugins of a host found into the pages directory.
+// The plugins of the pages are named [host]-[page].so.[version], and the binaries of the workers [host]-[page].bin.[version].
+func CleanVersions(hostname string, pagesdir string) {
+	hlogger := logger.GetHostLogger(hostname, "sys")
+	prefix := hostname + "-"
+	plugins := map[string]bool{}
+	filepath.Walk(pagesdir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasPrefix(info.Name(), prefix) {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if _, err := strconv.Atoi(strings.TrimPrefix(ext, ".")); err != nil {
+			return nil
+		}
+		base := strings.TrimSuffix(path, ext)
+		if strings.HasSuffix(base, ".so") || strings.HasSuffix(base, ".bin") {
+			plugins[base] = true
+		}
+		return nil
+	})
+	num := 0
+	for p := range plugins {
+		num += len(removeOldVersions(p))
+	}
+	hlogger.Println("Old versions of the compiled pages removed:", num)
+}
@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/config"
+)
+
+func TestVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	saved := config.Config
+	config.Config = &config.ConfigDef{Compiler: assets.Compiler{Retention: 2}}
+	defer func() { config.Config = saved }()
+
+	files := []string{
+		"developers-home.so.1", "developers-home.so.2", "developers-home.so.10", "developers-home.so.11",
+		"developers-home.so", "developers-home.so.0", "developers-home.so.x", "developers-home.so.1.tmp", "developers-home.go",
+		"developers-homepage.so.1", "admin-home.so.1", "admin-home.so.2", "admin-home.so.3",
+		"sub/developers-order.bin.1", "sub/developers-order.bin.2", "sub/developers-order.bin.3",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err = ioutil.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a directory is not a version
+	os.Mkdir(filepath.Join(dir, "developers-home.so.12"), 0755)
+
+	home := filepath.Join(dir, "developers-home.so")
+	if v := versions(home); len(v) != 4 || v[0] != 1 || v[3] != 11 {
+		t.Errorf("versions: got %v", v)
+	}
+	if v := LastVersion(home); v != 11 {
+		t.Errorf("last version: got %d, want 11", v)
+	}
+	if v := LastVersion(filepath.Join(dir, "unknown.so")); v != 0 {
+		t.Errorf("last version of a new plugin: got %d, want 0", v)
+	}
+
+	removed := removeOldVersions(home)
+	if len(removed) != 2 || removed[0] != home+".1" || removed[1] != home+".2" {
+		t.Errorf("removed: got %v", removed)
+	}
+
+	// only the plugins of the host are cleaned, in all the subdirectories
+	CleanVersions("developers", dir)
+	left := []string{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			left = append(left, rel)
+		}
+		return nil
+	})
+	sort.Strings(left)
+	want := []string{
+		"admin-home.so.1", "admin-home.so.2", "admin-home.so.3",
+		"developers-home.go", "developers-home.so", "developers-home.so.0", "developers-home.so.1.tmp", "developers-home.so.10", "developers-home.so.11", "developers-home.so.x",
+		"developers-homepage.so.1",
+		"sub/developers-order.bin.2", "sub/developers-order.bin.3",
+	}
+	if len(left) != len(want) {
+		t.Fatalf("files left: got %v, want %v", left, want)
+	}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Errorf("files left: got %v, want %v", left, want)
+			break
+		}
+	}
+}
@@ -2,9 +2,7 @@ package config
 
 import (
 	"encoding/json"
-	"errors"
 	"os"
-	"plugin"
 
 	"github.com/webability-go/xconfig"
 
@@ -13,20 +11,23 @@ import (
 )
 
 type Listener struct {
-	Name         string     `json:"name"`
-	IP           string     `json:"ip"`
-	Port         string     `json:"port"`
-	Protocol     string     `json:"protocol"`
-	ReadTimeOut  int        `json:"readtimeout"`
-	WriteTimeOut int        `json:"writetimeout"`
-	HeaderSize   int        `json:"headersize"`
-	Log          assets.Log `json:"log"`
+	Name         string         `json:"name"`
+	IP           string         `json:"ip"`
+	Port         string         `json:"port"`
+	Protocol     string         `json:"protocol"`
+	ReadTimeOut  int            `json:"readtimeout"`
+	WriteTimeOut int            `json:"writetimeout"`
+	HeaderSize   int            `json:"headersize"`
+	Log          assets.Log     `json:"log"`
+	Metrics      assets.Metrics `json:"metrics"`
+	Admin        assets.Admin   `json:"admin"`
 }
 
 type Engine struct {
 	Name    string `json:"name"`
 	Source  string `json:"source"`
 	Library string `json:"library"`
+	Code    string `json:"code"`
 }
 
 type Engines []Engine
@@ -40,11 +41,13 @@ type WListeners []Listener
 type ConfigDef struct {
 	Version   string
 	File      string
-	Listeners Listeners  `json:"listeners"`
-	Hosts     Hosts      `json:"hosts"`
-	Engines   Engines    `json:"engines"`
-	Log       assets.Log `json:"log"`
-	Include   []string   `json:"include"`
+	Listeners Listeners       `json:"listeners"`
+	Hosts     Hosts           `json:"hosts"`
+	Engines   Engines         `json:"engines"`
+	Log       assets.Log      `json:"log"`
+	Compiler  assets.Compiler `json:"compiler"`
+	Stats     assets.Stats    `json:"stats"`
+	Include   []string        `json:"include"`
 }
 
 var Config = &ConfigDef{}
@@ -129,40 +132,7 @@ func (c *ConfigDef) Load(file string) error {
 				}
 				c.Hosts[i].Config = lc
 
-				// creates user plugins
-				plugins, _ := c.Hosts[i].Config.Get("plugin")
-				if plugins != nil {
-					c.Hosts[i].Plugins = make(map[string]*plugin.Plugin)
-					c.Hosts[i].Applications = make(map[string]assets.Application)
-					c_plugins := plugins.(*xconfig.XConfig)
-					for app := range c_plugins.Parameters {
-						plugindata, _ := c_plugins.Get(app)
-						if plugindata != nil {
-							c_plugindata := plugindata.(*xconfig.XConfig)
-
-							p1, _ := c_plugindata.GetString("library")
-							lib, err := plugin.Open(p1)
-							if err != nil {
-								return err
-							}
-
-							// TODO(phil) Is not exists try to recompile (*Plugin)
-
-							application, err := lib.Lookup("Application")
-							if err != nil {
-								return err
-							}
-							interf, ok := application.(assets.Application)
-							if !ok {
-								return errors.New("Error linking application main interface Application, is not of type assets.Application.")
-							}
-
-							c.Hosts[i].Plugins[app] = lib
-							c.Hosts[i].Applications[app] = interf
-							interf.StartHost(c.Hosts[i])
-						}
-					}
-				}
+				// the applications (plugin entries) are loaded by the plugins manager once the loggers and the compiler are started
 			}
 		}
 	}
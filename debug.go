@@ -0,0 +1,159 @@
+package xamboo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/webability-go/xamboo/compiler"
+)
+
+// In debug mode (debug parameter of the host), the errors are rendered by the built-in debug renderer instead of the errorpage and errorblock of the host.
+// It shows the resolution chain of the pages, the compiler diagnostics, the stack of a panic and the data of the request.
+
+// trace keeps a step of the resolution of the pages, only in debug mode
+func (s *Server) trace(page string, format string, args ...interface{}) {
+	if !s.Host.Debug {
+		return
+	}
+	s.resolution = append(s.resolution, "["+page+"] "+fmt.Sprintf(format, args...))
+}
+
+type debugData struct {
+	Inner       bool
+	Page        string
+	Code        int
+	Status      string
+	Message     string
+	Resolution  []string
+	Diagnostics []compiler.Diagnostic
+	Compiler    string
+	Stack       string
+	Request     [][2]string
+	Headers     [][2]string
+	Form        [][2]string
+}
+
+const debugBlock = `<div style="font-family: monospace; font-size: 13px; border: 2px solid #c00; background: #fff6f6; color: #222; padding: 10px; margin: 5px; text-align: left;">
+<div style="font-size: 16px; font-weight: bold; color: #c00;">Error {{.Code}} {{.Status}} in {{.Page}}</div>
+<pre style="white-space: pre-wrap;">{{.Message}}</pre>
+{{if .Diagnostics}}<div style="font-weight: bold; margin-top: 10px;">Compiler diagnostics</div>
+<ul>{{range .Diagnostics}}<li>{{.String}}</li>{{end}}</ul>{{end}}
+{{if .Compiler}}<div style="font-weight: bold; margin-top: 10px;">Compiler output</div>
+<pre style="white-space: pre-wrap;">{{.Compiler}}</pre>{{end}}
+{{if .Stack}}<div style="font-weight: bold; margin-top: 10px;">Stack</div>
+<pre style="white-space: pre-wrap;">{{.Stack}}</pre>{{end}}
+{{if .Resolution}}<div style="font-weight: bold; margin-top: 10px;">Resolution of the pages</div>
+<ol>{{range .Resolution}}<li>{{.}}</li>{{end}}</ol>{{end}}
+{{if not .Inner}}<div style="font-weight: bold; margin-top: 10px;">Request</div>
+<table>{{range .Request}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}</table>
+{{if .Headers}}<div style="font-weight: bold; margin-top: 10px;">Headers</div>
+<table>{{range .Headers}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}</table>{{end}}
+{{if .Form}}<div style="font-weight: bold; margin-top: 10px;">Form</div>
+<table>{{range .Form}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}</table>{{end}}{{end}}
+</div>`
+
+const debugPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Error {{.Code}} {{.Status}}</title></head>
+<body>` + debugBlock + `</body></html>`
+
+// sensitiveHeaders are the headers whose values are redacted into the debug error. The fields of the form are redacted by name, as the secrets of the config.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+	"X-Csrf-Token":        true,
+}
+
+var debugTemplate = template.Must(template.New("page").Parse(debugPage))
+var debugBlockTemplate = template.Must(template.New("block").Parse(debugBlock))
+
+// renderDebugError renders the error with the built-in debug renderer: a full HTML page for the main page, a block for the inner pages
+func (s *Server) renderDebugError(page string, code int, innerpage bool, err error) string {
+	data := &debugData{
+		Inner:      innerpage,
+		Page:       page,
+		Code:       code,
+		Status:     http.StatusText(code),
+		Message:    err.Error(),
+		Resolution: s.resolution,
+	}
+	var cerr *compiler.Error
+	if errors.As(err, &cerr) {
+		data.Diagnostics = cerr.Diagnostics
+		data.Compiler = cerr.Output
+	}
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		data.Stack = string(perr.Stack)
+	}
+	if r := s.reader; r != nil && !innerpage {
+		data.Request = [][2]string{
+			{"Method", r.Method},
+			{"URL", redactedURL(r.URL)},
+			{"Protocol", r.Proto},
+			{"Host", r.Host},
+			{"Remote address", r.RemoteAddr},
+		}
+		if s.MainContext != nil {
+			data.Request = append(data.Request,
+				[2]string{"Main page", s.MainContext.MainPage + " (" + s.MainContext.MainPageUsed + ")"},
+				[2]string{"Language", s.MainContext.Language},
+				[2]string{"Version", s.MainContext.Version},
+			)
+		}
+		data.Headers = sortedValues(r.Header, func(name string) bool { return sensitiveHeaders[http.CanonicalHeaderKey(name)] })
+		data.Form = sortedValues(r.Form, func(name string) bool { return secretKeys[strings.ToLower(name)] })
+	}
+
+	t := debugBlockTemplate
+	if !innerpage {
+		t = debugTemplate
+		s.writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "Error rendering the debug error: " + err.Error()
+	}
+	return buf.String()
+}
+
+// redactedURL returns the URL with the values of the secret parameters of the query redacted
+func redactedURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for name := range query {
+		if secretKeys[strings.ToLower(name)] {
+			query.Set(name, "[redacted]")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = query.Encode()
+	return c.String()
+}
+
+// sortedValues returns the values of the headers or the form, ordered by name, with the values of the secret names redacted
+func sortedValues(values map[string][]string, secret func(name string) bool) [][2]string {
+	list := [][2]string{}
+	for name, v := range values {
+		if secret(name) {
+			list = append(list, [2]string{name, "[redacted]"})
+			continue
+		}
+		list = append(list, [2]string{name, strings.Join(v, ", ")})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i][0] < list[j][0] })
+	return list
+}
@@ -0,0 +1,61 @@
+package xamboo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestRenderDebugError(t *testing.T) {
+	host, dir, cleanup := testHost(t, testEngine{
+		"failed": func(ctx *assets.Context, e interface{}) interface{} {
+			ctx.Code = http.StatusInternalServerError
+			return errors.New("the page failed")
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return "main " + assets.EngineWrapperString(e, "failed", nil, "", "", "")
+		},
+	}, map[string]string{"failed": "", "main": ""})
+	defer cleanup()
+	host.Debug = true
+
+	secrets := []string{"Bearer s3cr3t", "session=s3cr3t", "k3y", "passw0rd"}
+	request := func(page string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/"+page+"?password=passw0rd&name=visible", nil)
+		r.Header.Set("Authorization", secrets[0])
+		r.Header.Set("Cookie", secrets[1])
+		r.Header.Set("X-API-Key", secrets[2])
+		r.Header.Set("User-Agent", "xamboo-test")
+		return testRequest(host, dir, r)
+	}
+
+	w := request("failed")
+	body := w.Body.String()
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("main page: got the code %d and the content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"<!DOCTYPE html>", "Error 500 Internal Server Error in failed", "the page failed", "failed.page found", "xamboo-test", "visible", "[redacted]"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("main page: %q not found into the debug error", want)
+		}
+	}
+	for _, secret := range secrets {
+		if strings.Contains(body, secret) {
+			t.Errorf("main page: the secret %q is shown into the debug error", secret)
+		}
+	}
+
+	// the inner blocks do not show the request
+	w = request("main")
+	body = w.Body.String()
+	if w.Code != http.StatusOK || !strings.HasPrefix(body, "main <div") || strings.Contains(body, "<!DOCTYPE html>") {
+		t.Errorf("inner block: got the code %d and the body %q", w.Code, body)
+	}
+	if !strings.Contains(body, "the page failed") || strings.Contains(body, "xamboo-test") {
+		t.Errorf("inner block: got %q", body)
+	}
+}
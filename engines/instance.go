@@ -11,7 +11,7 @@ import (
 	"github.com/webability-go/xamboo/utils"
 )
 
-var InstanceCache = xcore.NewXCache("instance", 0, 3600*time.Second)
+var InstanceCache = assets.RegisterCache(xcore.NewXCache("instance", 0, 3600*time.Second))
 
 func init() {
 	InstanceCache.Validator = utils.FileValidator
@@ -22,13 +22,20 @@ type Instance struct {
 }
 
 func (p *Instance) GetData(P string, i assets.Identity) *xconfig.XConfig {
+	data, _ := p.Load(P, i)
+	return data
+}
+
+// Load gets the .instance of P for the identity i from the cache or loads it from the pages directory.
+// It returns nil if the .instance does not exist, and the parsing error of the file if any.
+func (p *Instance) Load(P string, i assets.Identity) (*xconfig.XConfig, error) {
 	// build File Path:
 	lastpath := utils.LastPath(P)
 	filepath := p.PagesDir + P + "/" + lastpath + i.Stringify() + ".instance"
 
-	cdata, _ := InstanceCache.Get(filepath)
+	cdata, _ := assets.CacheGet(InstanceCache, filepath)
 	if cdata != nil {
-		return cdata.(*xconfig.XConfig)
+		return cdata.(*xconfig.XConfig), nil
 	}
 
 	// verify against souce CHANGES
@@ -36,11 +43,11 @@ func (p *Instance) GetData(P string, i assets.Identity) *xconfig.XConfig {
 	if utils.FileExists(filepath) {
 		// load the page instance
 		data := xconfig.New()
-		data.LoadFile(filepath)
+		err := data.LoadFile(filepath)
 
 		InstanceCache.Set(filepath, data)
-		return data
+		return data, err
 	}
 
-	return nil
+	return nil, nil
 }
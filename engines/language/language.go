@@ -11,7 +11,7 @@ import (
 	"github.com/webability-go/xamboo/utils"
 )
 
-var LanguageCache = xcore.NewXCache("language", 0, 3600*time.Second)
+var LanguageCache = assets.RegisterCache(xcore.NewXCache("language", 0, 3600*time.Second))
 
 func init() {
 	LanguageCache.Validator = utils.FileValidator
@@ -60,22 +60,39 @@ func (p *LanguageEngineInstance) NeedTemplate() bool {
 // params are an array of strings (if page from outside) or a mapped array of data (inner pages)
 func (p *LanguageEngineInstance) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
 
-	cdata, _ := LanguageCache.Get(p.FilePath)
+	data, err := p.load()
+	if err != nil {
+		ctx.Code = http.StatusInternalServerError
+		ctx.LoggerError.Println(err)
+		return err
+	}
+	if data != nil {
+		return data
+	}
+	return nil
+}
+
+// Load parses the .language and keeps it into the cache
+func (p *LanguageEngineInstance) Load(ctx *assets.Context) error {
+	_, err := p.load()
+	return err
+}
+
+func (p *LanguageEngineInstance) load() (*xcore.XLanguage, error) {
+
+	cdata, _ := assets.CacheGet(LanguageCache, p.FilePath)
 	if cdata != nil {
-		return cdata.(*xcore.XLanguage)
+		return cdata.(*xcore.XLanguage), nil
 	}
 
 	if utils.FileExists(p.FilePath) {
 		// load the language data
 		data, err := xcore.NewXLanguageFromXMLFile(p.FilePath)
 		if err != nil {
-			errortext := "Error loading language: " + p.FilePath + " " + err.Error()
-			ctx.Code = http.StatusInternalServerError
-			ctx.LoggerError.Println(errortext)
-			return errors.New(errortext)
+			return nil, errors.New("Error loading language: " + p.FilePath + " " + err.Error())
 		}
 		LanguageCache.Set(p.FilePath, data)
-		return data
+		return data, nil
 	}
-	return nil
+	return nil, nil
 }
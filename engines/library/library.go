@@ -1,23 +1,20 @@
 package library
 
 import (
-	"errors"
 	"net/http"
-	"os"
-	"os/exec"
-	"plugin"
 	//  "time"
 
 	"github.com/webability-go/xcore/v2"
 
 	"github.com/webability-go/xamboo/assets"
 	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/plugins"
 	"github.com/webability-go/xamboo/utils"
 )
 
 // no limits, no timeout (it's part of the code itself)
 // Will cache *Plugin objects
-var LibraryCache = xcore.NewXCache("library", 0, 0)
+var LibraryCache = assets.RegisterCache(xcore.NewXCache("library", 0, 0))
 
 /*
 func init() {
@@ -75,130 +72,32 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 	// IF THERE IS A NEW VERSION; CALL THE COMPILER THREAD (ONLY ONE) THAT WILL COMPILE THE CODE AND UPDATE THE CACHE MAP TO THE NEW VERSION.
 	// verify if the code is compiled.
 	// BE CAREFULL OF MEMORY OVERLOAD FOR NEW VERSION HOT LOADED (hotload = any flag in config ? authorized/not authorized, # authorized, send alerts, monitor etc)
-	var lib *assets.Plugin
-
-	// If the plugin is not loaded, load it (equivalent of cache for other types of server)
-	// verify if the code is loaded in memory
-	cdata, _ := LibraryCache.Get(p.SourcePath)
-	if cdata != nil {
-		lib = cdata.(*assets.Plugin)
-	} else {
-		lib = &assets.Plugin{
-			SourcePath:  p.SourcePath,
-			PluginPath:  p.PluginPath,
-			PluginVPath: p.PluginPath + ".1",
-			Version:     0, // will be 1 at first compile
-			Messages:    "",
-			Status:      0, // 0 = must compile or/and load (first creation of library)
-			Libs:        map[string]*plugin.Plugin{},
-		}
-	}
-
-	if !utils.FileExists(lib.SourcePath) {
-		if lib.Status != 2 {
-			lib.Status = 2
-			errortext := "Error: " + lib.SourcePath + " Source file does not exists.\n"
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-		}
+	lib, err := p.load(ctx)
+	if err != nil {
 		ctx.Code = http.StatusInternalServerError
-		return errors.New(lib.Messages)
-	}
-
-	mustcompile := true
-	if utils.FileExists(lib.PluginVPath) {
-		dp, _ := os.Stat(lib.PluginVPath)
-		dptime := dp.ModTime()
-		if utils.FileValidator(lib.SourcePath, dptime) {
-			mustcompile = false
-		}
-	}
-
-	if mustcompile {
-		lib.Status = 0
-		err := compiler.PleaseCompile(ctx, lib)
-		if err != nil {
-			lib.Status = 2
-			errortext := "Error: the GO code could not compile " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		}
-	}
-
-	if lib.Status == 0 { // needs to load the plugin
-		// Get GO BuildID to compare with in-memory GO BuildID and keep it with the library itself
-		// if already exists in memory, set it as default, or load it
-		buildid, err := getBuildId(lib.PluginVPath)
-		if err != nil {
-			errortext := "Error: the library .so does not have a build id " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		}
-		plg := lib.Libs[buildid]
-		if plg != nil { // already exists and loaded
-			lib.Lib = plg
-		} else {
-			lib.Lib, err = plugin.Open(lib.PluginVPath)
-			if err != nil {
-				lib.Status = 2
-				errortext := "Error: the library .so could not load " + lib.SourcePath + "\n" + err.Error()
-				ctx.LoggerError.Println(errortext)
-				lib.Messages += errortext
-				LibraryCache.Set(lib.SourcePath, lib)
-				ctx.Code = http.StatusInternalServerError
-				return errors.New(lib.Messages)
-			}
-			lib.Libs[buildid] = lib.Lib
-		}
-
-		fct, err := lib.Lib.Lookup("Run")
-		if err != nil {
-			lib.Status = 2
-			errortext := "Error: the called library does not contain a Run function " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		} else {
-			ok := false
-			lib.Run, ok = fct.(func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{})
-			if !ok {
-				lib.Status = 2
-				errortext := "Error: the called library does not contain a valid standard Run function " + lib.SourcePath + "\n" + err.Error()
-				ctx.LoggerError.Println(errortext)
-				lib.Messages += errortext
-				LibraryCache.Set(lib.SourcePath, lib)
-				ctx.Code = http.StatusInternalServerError
-				return errors.New(lib.Messages)
-			} else {
-				lib.Status = 1
-			}
-		}
-		LibraryCache.Set(lib.SourcePath, lib)
+		return err
 	}
+	lib.Lock()
+	run := lib.Run
+	lib.Unlock()
+	return run(ctx, template, language, e)
+}
 
-	if lib.Status == 1 {
-		return lib.Run(ctx, template, language, e)
-	}
-	// any error: return Messages
-	ctx.Code = http.StatusInternalServerError
-	return errors.New(lib.Messages)
+// Load compiles the library if needed and loads it in memory, without running it
+func (p *LibraryEngineInstance) Load(ctx *assets.Context) error {
+	_, err := p.load(ctx)
+	return err
 }
 
-func getBuildId(path string) (string, error) {
-	cmd := exec.Command("go", "tool", "buildid", path)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		// log error ?
-		return "", err
+func (p *LibraryEngineInstance) load(ctx *assets.Context) (*assets.Plugin, error) {
+	settings := compiler.BuildSettings(ctx.Sysparams)
+	switch {
+	case plugins.Interpreted(settings):
+		// development mode: the page is interpreted and not compiled
+		return plugins.InterpretLibrary(ctx, LibraryCache, p.SourcePath, p.PluginPath)
+	case plugins.OutOfProcess(settings):
+		// the page runs into its own worker process
+		return plugins.GetProcessLibrary(ctx, LibraryCache, p.SourcePath, p.PluginPath)
 	}
-	return string(out), nil
+	return plugins.GetLibrary(ctx, LibraryCache, p.SourcePath, p.PluginPath)
 }
@@ -4,10 +4,11 @@ import (
 	"github.com/webability-go/xconfig"
 	"github.com/webability-go/xcore/v2"
 
+	"github.com/webability-go/xamboo/assets"
 	"github.com/webability-go/xamboo/utils"
 )
 
-var PageCache = xcore.NewXCache("page", 0, 0)
+var PageCache = assets.RegisterCache(xcore.NewXCache("page", 0, 0))
 
 func init() {
 	PageCache.Validator = utils.FileValidator
@@ -19,15 +20,22 @@ type Page struct {
 }
 
 func (p *Page) GetData(P string) *xconfig.XConfig {
+	data, _ := p.Load(P)
+	return data
+}
+
+// Load gets the .page of P from the cache or loads it from the pages directory.
+// It returns nil if the .page does not exist, and the parsing error of the file if any.
+func (p *Page) Load(P string) (*xconfig.XConfig, error) {
 
 	// build File Path:
 	// separate last part
 	lastpath := utils.LastPath(P)
 	filepath := p.PagesDir + P + "/" + lastpath + ".page"
 
-	cdata, _ := PageCache.Get(filepath)
+	cdata, _ := assets.CacheGet(PageCache, filepath)
 	if cdata != nil {
-		return cdata.(*xconfig.XConfig)
+		return cdata.(*xconfig.XConfig), nil
 	}
 
 	// verify against souce CHANGES
@@ -35,15 +43,15 @@ func (p *Page) GetData(P string) *xconfig.XConfig {
 	if utils.FileExists(filepath) {
 		// load the page instance
 		data := xconfig.New()
-		data.LoadFile(filepath)
+		err := data.LoadFile(filepath)
 
 		if _, ok := data.Get("AcceptPathParameters"); !ok {
 			data.Set("AcceptPathParameters", p.AcceptPathParameters)
 		}
 
 		PageCache.Set(filepath, data)
-		return data
+		return data, err
 	}
 
-	return nil
+	return nil, nil
 }
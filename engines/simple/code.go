@@ -39,7 +39,7 @@ const (
 	MetaUnused = -1 // a "not used anymore" param to be freed
 )
 
-var CodeCache = xcore.NewXCache("code", 0, 0)
+var CodeCache = assets.RegisterCache(xcore.NewXCache("code", 0, 0))
 
 func init() {
 	CodeCache.Validator = utils.FileValidator
@@ -89,25 +89,36 @@ func (p *SimpleEngineInstance) NeedTemplate() bool {
 // params are an array of strings (if page from outside) or a mapped array of data (inner pages)
 func (p *SimpleEngineInstance) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
 
-	var compiled CodeData
-	cdata, _ := CodeCache.Get(p.FilePath)
-	if cdata != nil {
-		compiled = cdata.(CodeData)
-	} else {
-		data, err := ioutil.ReadFile(p.FilePath)
-		if err != nil {
-			errortext := "Error; .code file unavailable " + p.FilePath
-			ctx.Code = http.StatusInternalServerError
-			ctx.LoggerError.Println(errortext)
-			return errors.New(errortext)
-		}
-		compiled = compileCode(string(data))
-		CodeCache.Set(p.FilePath, compiled)
+	compiled, err := p.load()
+	if err != nil {
+		ctx.Code = http.StatusInternalServerError
+		ctx.LoggerError.Println(err)
+		return err
 	}
 
 	return compiled.Inject(ctx, language, e)
 }
 
+// Load compiles the .code and keeps it into the cache, without running it
+func (p *SimpleEngineInstance) Load(ctx *assets.Context) error {
+	_, err := p.load()
+	return err
+}
+
+func (p *SimpleEngineInstance) load() (CodeData, error) {
+	cdata, _ := assets.CacheGet(CodeCache, p.FilePath)
+	if cdata != nil {
+		return cdata.(CodeData), nil
+	}
+	data, err := ioutil.ReadFile(p.FilePath)
+	if err != nil {
+		return nil, errors.New("Error; .code file unavailable " + p.FilePath)
+	}
+	compiled := compileCode(string(data))
+	CodeCache.Set(p.FilePath, compiled)
+	return compiled, nil
+}
+
 type CodeParam struct {
 	paramtype int
 	data1     string
@@ -10,7 +10,7 @@ import (
 	"github.com/webability-go/xamboo/utils"
 )
 
-var TemplateCache = xcore.NewXCache("template", 0, 3600*time.Second)
+var TemplateCache = assets.RegisterCache(xcore.NewXCache("template", 0, 3600*time.Second))
 
 func init() {
 	TemplateCache.Validator = utils.FileValidator
@@ -60,18 +60,33 @@ func (p *TemplateEngineInstance) NeedTemplate() bool {
 // params are an array of strings (if page from outside) or a mapped array of data (inner pages)
 func (p *TemplateEngineInstance) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
 
-	cdata, _ := TemplateCache.Get(p.FilePath)
+	data, _ := p.load()
+	if data != nil {
+		return data
+	}
+	return nil
+}
+
+// Load compiles the .template and keeps it into the cache
+func (p *TemplateEngineInstance) Load(ctx *assets.Context) error {
+	_, err := p.load()
+	return err
+}
+
+func (p *TemplateEngineInstance) load() (*xcore.XTemplate, error) {
+
+	cdata, _ := assets.CacheGet(TemplateCache, p.FilePath)
 	if cdata != nil {
-		return cdata.(*xcore.XTemplate)
+		return cdata.(*xcore.XTemplate), nil
 	}
 
 	if utils.FileExists(p.FilePath) {
 		// load the template data
 		data := xcore.NewXTemplate()
-		data.LoadFile(p.FilePath)
+		err := data.LoadFile(p.FilePath)
 
 		TemplateCache.Set(p.FilePath, data)
-		return data
+		return data, err
 	}
-	return nil
+	return nil, nil
 }
@@ -5,9 +5,6 @@ import (
 	"encoding/xml"
 	"errors"
 	"net/http"
-	"os"
-	"os/exec"
-	"plugin"
 	"strings"
 	//  "time"
 
@@ -15,13 +12,13 @@ import (
 	"github.com/webability-go/xcore/v2"
 
 	"github.com/webability-go/xamboo/assets"
-	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/plugins"
 	"github.com/webability-go/xamboo/utils"
 )
 
 // no limits, no timeout (it's part of the code itself)
 // Will cache *Plugin objects
-var LibraryCache = xcore.NewXCache("wajafapp", 0, 0)
+var LibraryCache = assets.RegisterCache(xcore.NewXCache("wajafapp", 0, 0))
 
 /*
 func init() {
@@ -78,119 +75,10 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 	// verify if the code is compiled.
 	// IF THERE IS A NEW VERSION; CALL THE COMPILER THREAD (ONLY ONE) THAT WILL COMPILE THE CODE AND UPDATE THE CACHE MAP TO THE NEW VERSION.
 	// BE CAREFULL OF MEMORY OVERLOAD FOR NEW VERSION HOT LOADED (hotload = any flag in config ? authorized/not authorized, # authorized, send alerts, monitor etc)
-	var lib *assets.Plugin
-	var err error
-
-	// If the plugin is not loaded, load it (equivalent of cache for other types of server)
-	// verify if the code is loaded in memory
-	cdata, _ := LibraryCache.Get(p.SourcePath)
-	if cdata != nil {
-		lib = cdata.(*assets.Plugin)
-	} else {
-		lib = &assets.Plugin{
-			SourcePath:  p.SourcePath,
-			PluginPath:  p.PluginPath,
-			PluginVPath: p.PluginPath + ".1",
-			Version:     0, // will be 1 at first compile
-			Messages:    "",
-			Status:      0, // 0 = must compile or/and load (first creation of library)
-			Libs:        map[string]*plugin.Plugin{},
-		}
-	}
-
-	if !utils.FileExists(lib.SourcePath) {
-		if lib.Status != 2 {
-			lib.Status = 2
-			errortext := "Error: " + lib.SourcePath + " Source file does not exists.\n"
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-		}
+	lib, err := p.load(ctx)
+	if err != nil {
 		ctx.Code = http.StatusInternalServerError
-		return errors.New(lib.Messages)
-	}
-
-	mustcompile := true
-	if utils.FileExists(lib.PluginVPath) {
-		dp, _ := os.Stat(lib.PluginVPath)
-		dptime := dp.ModTime()
-		if utils.FileValidator(lib.SourcePath, dptime) {
-			mustcompile = false
-		}
-	}
-
-	if mustcompile {
-		lib.Status = 0
-		err := compiler.PleaseCompile(ctx, lib)
-		if err != nil {
-			lib.Status = 2
-			errortext := "Error: the GO code could not compile " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		}
-	}
-
-	if lib.Status == 0 { // needs to load the plugin
-		// Get GO BuildID to compare with in-memory GO BuildID and keep it with the library itself
-		// if already exists in memory, set it as default, or load it
-		buildid, err := getBuildId(lib.PluginVPath)
-		if err != nil {
-			errortext := "Error: the library .so does not have a build id " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		}
-		plg := lib.Libs[buildid]
-		if plg != nil { // already exists and loaded
-			lib.Lib = plg
-		} else {
-			lib.Lib, err = plugin.Open(lib.PluginVPath)
-			if err != nil {
-				lib.Status = 2
-				errortext := "Error: the library .so could not load " + lib.SourcePath + "\n" + err.Error()
-				ctx.LoggerError.Println(errortext)
-				lib.Messages += errortext
-				LibraryCache.Set(lib.SourcePath, lib)
-				ctx.Code = http.StatusInternalServerError
-				return errors.New(lib.Messages)
-			}
-			lib.Libs[buildid] = lib.Lib
-		}
-
-		fct, err := lib.Lib.Lookup("Run")
-		if err != nil {
-			lib.Status = 2
-			errortext := "Error: the called library does not contain a Run function " + lib.SourcePath + "\n" + err.Error()
-			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
-		} else {
-			ok := false
-			lib.Run, ok = fct.(func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{})
-			if !ok {
-				lib.Status = 2
-				errortext := "Error: the called library does not contain a valid standard Run function " + lib.SourcePath + "\n" + err.Error()
-				ctx.LoggerError.Println(errortext)
-				lib.Messages += errortext
-				LibraryCache.Set(lib.SourcePath, lib)
-				ctx.Code = http.StatusInternalServerError
-				return errors.New(lib.Messages)
-			} else {
-				lib.Status = 1
-			}
-		}
-		LibraryCache.Set(lib.SourcePath, lib)
-	}
-
-	if lib.Status != 1 {
-		return lib.Messages
+		return err
 	}
 
 	fctname := "Run"
@@ -206,24 +94,13 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 		out = ctx.MainURLparams[1]
 	}
 
-	fct, err := lib.Lib.Lookup(fctname)
+	lib.Lock()
+	xfct, err := plugins.LookupPageFunction(lib.Lib, fctname)
+	lib.Unlock()
 	if err != nil {
-		errortext := "Error: the called library does not contain a function " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
+		errortext := "Error: " + err.Error() + " in " + lib.SourcePath + "\n"
 		ctx.LoggerError.Println(errortext)
-		lib.Messages += errortext
-		LibraryCache.Set(lib.SourcePath, lib)
-		ctx.Code = http.StatusInternalServerError
-		return errors.New(lib.Messages)
-	}
-
-	xfct, ok := fct.(func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{})
-	if !ok {
-		errortext := "Error: the called library does not contain a valid standard function " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
-		ctx.LoggerError.Println(errortext)
-		lib.Messages += errortext
-		LibraryCache.Set(lib.SourcePath, lib)
-		ctx.Code = http.StatusInternalServerError
-		return errors.New(lib.Messages)
+		return runError(ctx, lib, errortext)
 	}
 
 	x1 := xfct(ctx, template, language, e)
@@ -248,20 +125,14 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 				if err != nil {
 					errortext := "Error: unmarshalling the XML code in " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
 					ctx.LoggerError.Println(errortext)
-					lib.Messages += errortext
-					LibraryCache.Set(lib.SourcePath, lib)
-					ctx.Code = http.StatusInternalServerError
-					return errors.New(lib.Messages)
+					return runError(ctx, lib, errortext)
 				}
 
 				json, err := json.Marshal(app)
 				if err != nil {
 					errortext := "Error: marshalling the JSON code in " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
 					ctx.LoggerError.Println(errortext)
-					lib.Messages += errortext
-					LibraryCache.Set(lib.SourcePath, lib)
-					ctx.Code = http.StatusInternalServerError
-					return errors.New(lib.Messages)
+					return runError(ctx, lib, errortext)
 				}
 				return string(json)
 			}
@@ -277,10 +148,7 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 			if err != nil {
 				errortext := "Error: marshalling the JSON code in " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
 				ctx.LoggerError.Println(errortext)
-				lib.Messages += errortext
-				LibraryCache.Set(lib.SourcePath, lib)
-				ctx.Code = http.StatusInternalServerError
-				return errors.New(lib.Messages)
+				return runError(ctx, lib, errortext)
 			}
 			return string(json)
 		}
@@ -289,10 +157,7 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 		if err != nil {
 			errortext := "Error: marshalling the JSON code in " + fctname + " in " + lib.SourcePath + "\n" + err.Error()
 			ctx.LoggerError.Println(errortext)
-			lib.Messages += errortext
-			LibraryCache.Set(lib.SourcePath, lib)
-			ctx.Code = http.StatusInternalServerError
-			return errors.New(lib.Messages)
+			return runError(ctx, lib, errortext)
 		}
 		return string(json)
 	}
@@ -300,12 +165,22 @@ func (p *LibraryEngineInstance) Run(ctx *assets.Context, template *xcore.XTempla
 	return x1
 }
 
-func getBuildId(path string) (string, error) {
-	cmd := exec.Command("go", "tool", "buildid", path)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		// log error ?
-		return "", err
-	}
-	return string(out), nil
+// Load compiles the library if needed and loads it in memory, without running it
+func (p *LibraryEngineInstance) Load(ctx *assets.Context) error {
+	_, err := p.load(ctx)
+	return err
+}
+
+func (p *LibraryEngineInstance) load(ctx *assets.Context) (*assets.Plugin, error) {
+	return plugins.GetLibrary(ctx, LibraryCache, p.SourcePath, p.PluginPath)
+}
+
+// runError adds the error to the messages of the library and returns them as the error of the page
+func runError(ctx *assets.Context, lib *assets.Plugin, errortext string) error {
+	lib.Lock()
+	lib.Messages += errortext
+	messages := lib.Messages
+	lib.Unlock()
+	ctx.Code = http.StatusInternalServerError
+	return errors.New(messages)
 }
@@ -6,6 +6,7 @@ require (
 	github.com/avct/uasurfer v0.0.0-20191028135549-26b5daa857f1
 	github.com/tdewolff/minify v2.3.6+incompatible
 	github.com/tdewolff/parse v2.3.4+incompatible // indirect
+	github.com/traefik/yaegi v0.9.21
 	github.com/webability-go/wajaf v0.0.15
 	github.com/webability-go/xconfig v0.4.2
 	github.com/webability-go/xcore/v2 v2.0.4
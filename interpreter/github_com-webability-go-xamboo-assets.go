@@ -0,0 +1,291 @@
+// Code generated by 'yaegi extract github.com/webability-go/xamboo/assets'. DO NOT EDIT.
+
+package interpreter
+
+import (
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xconfig"
+	"github.com/webability-go/xcore/v2"
+	"github.com/webability-go/xdominion"
+	"golang.org/x/text/language"
+	"log"
+	"reflect"
+)
+
+func init() {
+	Symbols["github.com/webability-go/xamboo/assets/assets"] = map[string]reflect.Value{
+		// function, constant and variable definitions
+		"CacheGet":            reflect.ValueOf(assets.CacheGet),
+		"EngineWrapper":       reflect.ValueOf(&assets.EngineWrapper).Elem(),
+		"EngineWrapperString": reflect.ValueOf(&assets.EngineWrapperString).Elem(),
+		"GetCaches":           reflect.ValueOf(assets.GetCaches),
+		"LogDebug":            reflect.ValueOf(assets.LogDebug),
+		"LogError":            reflect.ValueOf(assets.LogError),
+		"LogInfo":             reflect.ValueOf(assets.LogInfo),
+		"LogWarn":             reflect.ValueOf(assets.LogWarn),
+		"ParseLogLevel":       reflect.ValueOf(assets.ParseLogLevel),
+		"RegisterCache":       reflect.ValueOf(assets.RegisterCache),
+
+		// type definitions
+		"Admin":                reflect.ValueOf((*assets.Admin)(nil)),
+		"Application":          reflect.ValueOf((*assets.Application)(nil)),
+		"Auth":                 reflect.ValueOf((*assets.Auth)(nil)),
+		"Browser":              reflect.ValueOf((*assets.Browser)(nil)),
+		"Build":                reflect.ValueOf((*assets.Build)(nil)),
+		"CacheStatus":          reflect.ValueOf((*assets.CacheStatus)(nil)),
+		"Compiler":             reflect.ValueOf((*assets.Compiler)(nil)),
+		"Context":              reflect.ValueOf((*assets.Context)(nil)),
+		"Datasource":           reflect.ValueOf((*assets.Datasource)(nil)),
+		"DatasourceSet":        reflect.ValueOf((*assets.DatasourceSet)(nil)),
+		"Engine":               reflect.ValueOf((*assets.Engine)(nil)),
+		"EngineInstance":       reflect.ValueOf((*assets.EngineInstance)(nil)),
+		"EngineInstanceLoader": reflect.ValueOf((*assets.EngineInstanceLoader)(nil)),
+		"GZip":                 reflect.ValueOf((*assets.GZip)(nil)),
+		"Host":                 reflect.ValueOf((*assets.Host)(nil)),
+		"Identity":             reflect.ValueOf((*assets.Identity)(nil)),
+		"LevelLogger":          reflect.ValueOf((*assets.LevelLogger)(nil)),
+		"Log":                  reflect.ValueOf((*assets.Log)(nil)),
+		"LogLevel":             reflect.ValueOf((*assets.LogLevel)(nil)),
+		"Metrics":              reflect.ValueOf((*assets.Metrics)(nil)),
+		"Minify":               reflect.ValueOf((*assets.Minify)(nil)),
+		"Module":               reflect.ValueOf((*assets.Module)(nil)),
+		"ModuleSet":            reflect.ValueOf((*assets.ModuleSet)(nil)),
+		"OriginDef":            reflect.ValueOf((*assets.OriginDef)(nil)),
+		"Plugin":               reflect.ValueOf((*assets.Plugin)(nil)),
+		"Redirect":             reflect.ValueOf((*assets.Redirect)(nil)),
+		"Stats":                reflect.ValueOf((*assets.Stats)(nil)),
+		"UserAgent":            reflect.ValueOf((*assets.UserAgent)(nil)),
+		"Warmup":               reflect.ValueOf((*assets.Warmup)(nil)),
+		"Watcher":              reflect.ValueOf((*assets.Watcher)(nil)),
+
+		// interface wrapper definitions
+		"_Application":          reflect.ValueOf((*_github_com_webability_go_xamboo_assets_Application)(nil)),
+		"_Datasource":           reflect.ValueOf((*_github_com_webability_go_xamboo_assets_Datasource)(nil)),
+		"_DatasourceSet":        reflect.ValueOf((*_github_com_webability_go_xamboo_assets_DatasourceSet)(nil)),
+		"_Engine":               reflect.ValueOf((*_github_com_webability_go_xamboo_assets_Engine)(nil)),
+		"_EngineInstance":       reflect.ValueOf((*_github_com_webability_go_xamboo_assets_EngineInstance)(nil)),
+		"_EngineInstanceLoader": reflect.ValueOf((*_github_com_webability_go_xamboo_assets_EngineInstanceLoader)(nil)),
+		"_Module":               reflect.ValueOf((*_github_com_webability_go_xamboo_assets_Module)(nil)),
+		"_ModuleSet":            reflect.ValueOf((*_github_com_webability_go_xamboo_assets_ModuleSet)(nil)),
+	}
+}
+
+// _github_com_webability_go_xamboo_assets_Application is an interface wrapper for Application type
+type _github_com_webability_go_xamboo_assets_Application struct {
+	IValue                    interface{}
+	WGetCompiledModules       func() assets.ModuleSet
+	WGetDatasourceSet         func() assets.DatasourceSet
+	WGetDatasourcesConfigFile func() string
+	WStartContext             func(ctx *assets.Context)
+	WStartHost                func(h assets.Host)
+}
+
+func (W _github_com_webability_go_xamboo_assets_Application) GetCompiledModules() assets.ModuleSet {
+	return W.WGetCompiledModules()
+}
+func (W _github_com_webability_go_xamboo_assets_Application) GetDatasourceSet() assets.DatasourceSet {
+	return W.WGetDatasourceSet()
+}
+func (W _github_com_webability_go_xamboo_assets_Application) GetDatasourcesConfigFile() string {
+	return W.WGetDatasourcesConfigFile()
+}
+func (W _github_com_webability_go_xamboo_assets_Application) StartContext(ctx *assets.Context) {
+	W.WStartContext(ctx)
+}
+func (W _github_com_webability_go_xamboo_assets_Application) StartHost(h assets.Host) {
+	W.WStartHost(h)
+}
+
+// _github_com_webability_go_xamboo_assets_Datasource is an interface wrapper for Datasource type
+type _github_com_webability_go_xamboo_assets_Datasource struct {
+	IValue              interface{}
+	WAddLanguage        func(lang language.Tag)
+	WGetCache           func(id string) *xcore.XCache
+	WGetCaches          func() map[string]*xcore.XCache
+	WGetDatabase        func() *xdominion.XBase
+	WGetLanguages       func() []language.Tag
+	WGetLog             func(id string) *log.Logger
+	WGetLogs            func() map[string]*log.Logger
+	WGetModule          func(moduleid string) string
+	WGetModules         func() map[string]string
+	WGetName            func() string
+	WGetTable           func(id string) *xdominion.XTable
+	WGetTables          func() map[string]*xdominion.XTable
+	WIsModuleAuthorized func(id string) bool
+	WLog                func(id string, messages ...interface{})
+	WSetCache           func(id string, cache *xcore.XCache)
+	WSetDatabase        func(db *xdominion.XBase)
+	WSetLog             func(id string, logger *log.Logger)
+	WSetModule          func(moduleid string, moduleversion string)
+	WSetTable           func(id string, table *xdominion.XTable)
+}
+
+func (W _github_com_webability_go_xamboo_assets_Datasource) AddLanguage(lang language.Tag) {
+	W.WAddLanguage(lang)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetCache(id string) *xcore.XCache {
+	return W.WGetCache(id)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetCaches() map[string]*xcore.XCache {
+	return W.WGetCaches()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetDatabase() *xdominion.XBase {
+	return W.WGetDatabase()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetLanguages() []language.Tag {
+	return W.WGetLanguages()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetLog(id string) *log.Logger {
+	return W.WGetLog(id)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetLogs() map[string]*log.Logger {
+	return W.WGetLogs()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetModule(moduleid string) string {
+	return W.WGetModule(moduleid)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetModules() map[string]string {
+	return W.WGetModules()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetName() string { return W.WGetName() }
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetTable(id string) *xdominion.XTable {
+	return W.WGetTable(id)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) GetTables() map[string]*xdominion.XTable {
+	return W.WGetTables()
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) IsModuleAuthorized(id string) bool {
+	return W.WIsModuleAuthorized(id)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) Log(id string, messages ...interface{}) {
+	W.WLog(id, messages...)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) SetCache(id string, cache *xcore.XCache) {
+	W.WSetCache(id, cache)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) SetDatabase(db *xdominion.XBase) {
+	W.WSetDatabase(db)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) SetLog(id string, logger *log.Logger) {
+	W.WSetLog(id, logger)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) SetModule(moduleid string, moduleversion string) {
+	W.WSetModule(moduleid, moduleversion)
+}
+func (W _github_com_webability_go_xamboo_assets_Datasource) SetTable(id string, table *xdominion.XTable) {
+	W.WSetTable(id, table)
+}
+
+// _github_com_webability_go_xamboo_assets_DatasourceSet is an interface wrapper for DatasourceSet type
+type _github_com_webability_go_xamboo_assets_DatasourceSet struct {
+	IValue            interface{}
+	WCreateDatasource func(name string, config *xconfig.XConfig) (assets.Datasource, error)
+	WGetDatasource    func(id string) assets.Datasource
+	WGetDatasources   func() map[string]assets.Datasource
+	WSetDatasource    func(id string, ctx assets.Datasource)
+	WTryDatasource    func(ctx *assets.Context, defaultdatasourcename string) assets.Datasource
+}
+
+func (W _github_com_webability_go_xamboo_assets_DatasourceSet) CreateDatasource(name string, config *xconfig.XConfig) (assets.Datasource, error) {
+	return W.WCreateDatasource(name, config)
+}
+func (W _github_com_webability_go_xamboo_assets_DatasourceSet) GetDatasource(id string) assets.Datasource {
+	return W.WGetDatasource(id)
+}
+func (W _github_com_webability_go_xamboo_assets_DatasourceSet) GetDatasources() map[string]assets.Datasource {
+	return W.WGetDatasources()
+}
+func (W _github_com_webability_go_xamboo_assets_DatasourceSet) SetDatasource(id string, ctx assets.Datasource) {
+	W.WSetDatasource(id, ctx)
+}
+func (W _github_com_webability_go_xamboo_assets_DatasourceSet) TryDatasource(ctx *assets.Context, defaultdatasourcename string) assets.Datasource {
+	return W.WTryDatasource(ctx, defaultdatasourcename)
+}
+
+// _github_com_webability_go_xamboo_assets_Engine is an interface wrapper for Engine type
+type _github_com_webability_go_xamboo_assets_Engine struct {
+	IValue        interface{}
+	WGetInstance  func(Hostname string, PagesDir string, P string, i assets.Identity) assets.EngineInstance
+	WNeedInstance func() bool
+	WRun          func(ctx *assets.Context, e interface{}) interface{}
+}
+
+func (W _github_com_webability_go_xamboo_assets_Engine) GetInstance(Hostname string, PagesDir string, P string, i assets.Identity) assets.EngineInstance {
+	return W.WGetInstance(Hostname, PagesDir, P, i)
+}
+func (W _github_com_webability_go_xamboo_assets_Engine) NeedInstance() bool { return W.WNeedInstance() }
+func (W _github_com_webability_go_xamboo_assets_Engine) Run(ctx *assets.Context, e interface{}) interface{} {
+	return W.WRun(ctx, e)
+}
+
+// _github_com_webability_go_xamboo_assets_EngineInstance is an interface wrapper for EngineInstance type
+type _github_com_webability_go_xamboo_assets_EngineInstance struct {
+	IValue        interface{}
+	WNeedLanguage func() bool
+	WNeedTemplate func() bool
+	WRun          func(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{}
+}
+
+func (W _github_com_webability_go_xamboo_assets_EngineInstance) NeedLanguage() bool {
+	return W.WNeedLanguage()
+}
+func (W _github_com_webability_go_xamboo_assets_EngineInstance) NeedTemplate() bool {
+	return W.WNeedTemplate()
+}
+func (W _github_com_webability_go_xamboo_assets_EngineInstance) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
+	return W.WRun(ctx, template, language, e)
+}
+
+// _github_com_webability_go_xamboo_assets_EngineInstanceLoader is an interface wrapper for EngineInstanceLoader type
+type _github_com_webability_go_xamboo_assets_EngineInstanceLoader struct {
+	IValue interface{}
+	WLoad  func(ctx *assets.Context) error
+}
+
+func (W _github_com_webability_go_xamboo_assets_EngineInstanceLoader) Load(ctx *assets.Context) error {
+	return W.WLoad(ctx)
+}
+
+// _github_com_webability_go_xamboo_assets_Module is an interface wrapper for Module type
+type _github_com_webability_go_xamboo_assets_Module struct {
+	IValue               interface{}
+	WGetID               func() string
+	WGetInstalledVersion func(a0 assets.Datasource) string
+	WGetLanguages        func() map[language.Tag]string
+	WGetNeeds            func() []string
+	WGetVersion          func() string
+	WSetup               func(a0 assets.Datasource, a1 string) ([]string, error)
+	WStartContext        func(a0 assets.Datasource, a1 *assets.Context) error
+	WSynchronize         func(a0 assets.Datasource, a1 string) ([]string, error)
+}
+
+func (W _github_com_webability_go_xamboo_assets_Module) GetID() string { return W.WGetID() }
+func (W _github_com_webability_go_xamboo_assets_Module) GetInstalledVersion(a0 assets.Datasource) string {
+	return W.WGetInstalledVersion(a0)
+}
+func (W _github_com_webability_go_xamboo_assets_Module) GetLanguages() map[language.Tag]string {
+	return W.WGetLanguages()
+}
+func (W _github_com_webability_go_xamboo_assets_Module) GetNeeds() []string { return W.WGetNeeds() }
+func (W _github_com_webability_go_xamboo_assets_Module) GetVersion() string { return W.WGetVersion() }
+func (W _github_com_webability_go_xamboo_assets_Module) Setup(a0 assets.Datasource, a1 string) ([]string, error) {
+	return W.WSetup(a0, a1)
+}
+func (W _github_com_webability_go_xamboo_assets_Module) StartContext(a0 assets.Datasource, a1 *assets.Context) error {
+	return W.WStartContext(a0, a1)
+}
+func (W _github_com_webability_go_xamboo_assets_Module) Synchronize(a0 assets.Datasource, a1 string) ([]string, error) {
+	return W.WSynchronize(a0, a1)
+}
+
+// _github_com_webability_go_xamboo_assets_ModuleSet is an interface wrapper for ModuleSet type
+type _github_com_webability_go_xamboo_assets_ModuleSet struct {
+	IValue    interface{}
+	WGet      func(id string) assets.Module
+	WRegister func(m assets.Module)
+}
+
+func (W _github_com_webability_go_xamboo_assets_ModuleSet) Get(id string) assets.Module {
+	return W.WGet(id)
+}
+func (W _github_com_webability_go_xamboo_assets_ModuleSet) Register(m assets.Module) { W.WRegister(m) }
@@ -0,0 +1,98 @@
+// Code generated by 'yaegi extract github.com/webability-go/xconfig'. DO NOT EDIT.
+
+package interpreter
+
+import (
+	"github.com/webability-go/xconfig"
+	"github.com/webability-go/xcore/v2"
+	"go/constant"
+	"go/token"
+	"reflect"
+	"time"
+)
+
+func init() {
+	Symbols["github.com/webability-go/xconfig/xconfig"] = map[string]reflect.Value{
+		// function, constant and variable definitions
+		"New":     reflect.ValueOf(xconfig.New),
+		"VERSION": reflect.ValueOf(constant.MakeFromLiteral("\"0.4.2\"", token.STRING, 0)),
+
+		// type definitions
+		"Parameter":  reflect.ValueOf((*xconfig.Parameter)(nil)),
+		"XConfig":    reflect.ValueOf((*xconfig.XConfig)(nil)),
+		"XConfigDef": reflect.ValueOf((*xconfig.XConfigDef)(nil)),
+
+		// interface wrapper definitions
+		"_XConfigDef": reflect.ValueOf((*_github_com_webability_go_xconfig_XConfigDef)(nil)),
+	}
+}
+
+// _github_com_webability_go_xconfig_XConfigDef is an interface wrapper for XConfigDef type
+type _github_com_webability_go_xconfig_XConfigDef struct {
+	IValue               interface{}
+	WClone               func() xcore.XDatasetDef
+	WDel                 func(key string)
+	WGet                 func(key string) (interface{}, bool)
+	WGetBool             func(key string) (bool, bool)
+	WGetBoolCollection   func(key string) ([]bool, bool)
+	WGetCollection       func(key string) (xcore.XDatasetCollectionDef, bool)
+	WGetDataset          func(key string) (xcore.XDatasetDef, bool)
+	WGetFloat            func(key string) (float64, bool)
+	WGetFloatCollection  func(key string) ([]float64, bool)
+	WGetInt              func(key string) (int, bool)
+	WGetIntCollection    func(key string) ([]int, bool)
+	WGetString           func(key string) (string, bool)
+	WGetStringCollection func(key string) ([]string, bool)
+	WGetTime             func(key string) (time.Time, bool)
+	WGetTimeCollection   func(key string) ([]time.Time, bool)
+	WGoString            func() string
+	WSet                 func(key string, data interface{})
+	WString              func() string
+}
+
+func (W _github_com_webability_go_xconfig_XConfigDef) Clone() xcore.XDatasetDef { return W.WClone() }
+func (W _github_com_webability_go_xconfig_XConfigDef) Del(key string)           { W.WDel(key) }
+func (W _github_com_webability_go_xconfig_XConfigDef) Get(key string) (interface{}, bool) {
+	return W.WGet(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetBool(key string) (bool, bool) {
+	return W.WGetBool(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetBoolCollection(key string) ([]bool, bool) {
+	return W.WGetBoolCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetCollection(key string) (xcore.XDatasetCollectionDef, bool) {
+	return W.WGetCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetDataset(key string) (xcore.XDatasetDef, bool) {
+	return W.WGetDataset(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetFloat(key string) (float64, bool) {
+	return W.WGetFloat(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetFloatCollection(key string) ([]float64, bool) {
+	return W.WGetFloatCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetInt(key string) (int, bool) {
+	return W.WGetInt(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetIntCollection(key string) ([]int, bool) {
+	return W.WGetIntCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetString(key string) (string, bool) {
+	return W.WGetString(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetStringCollection(key string) ([]string, bool) {
+	return W.WGetStringCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetTime(key string) (time.Time, bool) {
+	return W.WGetTime(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GetTimeCollection(key string) ([]time.Time, bool) {
+	return W.WGetTimeCollection(key)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) GoString() string { return W.WGoString() }
+func (W _github_com_webability_go_xconfig_XConfigDef) Set(key string, data interface{}) {
+	W.WSet(key, data)
+}
+func (W _github_com_webability_go_xconfig_XConfigDef) String() string { return W.WString() }
@@ -0,0 +1,194 @@
+// Code generated by 'yaegi extract github.com/webability-go/xcore/v2'. DO NOT EDIT.
+
+package interpreter
+
+import (
+	"github.com/webability-go/xcore/v2"
+	"go/constant"
+	"go/token"
+	"reflect"
+	"time"
+)
+
+func init() {
+	Symbols["github.com/webability-go/xcore/v2/xcore"] = map[string]reflect.Value{
+		// function, constant and variable definitions
+		"LOG":                       reflect.ValueOf(&xcore.LOG).Elem(),
+		"MetaComment":               reflect.ValueOf(constant.MakeFromLiteral("1", token.INT, 0)),
+		"MetaCondition":             reflect.ValueOf(constant.MakeFromLiteral("5", token.INT, 0)),
+		"MetaDump":                  reflect.ValueOf(constant.MakeFromLiteral("6", token.INT, 0)),
+		"MetaLanguage":              reflect.ValueOf(constant.MakeFromLiteral("2", token.INT, 0)),
+		"MetaRange":                 reflect.ValueOf(constant.MakeFromLiteral("4", token.INT, 0)),
+		"MetaReference":             reflect.ValueOf(constant.MakeFromLiteral("3", token.INT, 0)),
+		"MetaString":                reflect.ValueOf(constant.MakeFromLiteral("0", token.INT, 0)),
+		"MetaTemplateEnd":           reflect.ValueOf(constant.MakeFromLiteral("102", token.INT, 0)),
+		"MetaTemplateStart":         reflect.ValueOf(constant.MakeFromLiteral("101", token.INT, 0)),
+		"MetaUnused":                reflect.ValueOf(constant.MakeFromLiteral("-1", token.INT, 0)),
+		"MetaVariable":              reflect.ValueOf(constant.MakeFromLiteral("7", token.INT, 0)),
+		"NewXCache":                 reflect.ValueOf(xcore.NewXCache),
+		"NewXDatasetTS":             reflect.ValueOf(xcore.NewXDatasetTS),
+		"NewXLanguage":              reflect.ValueOf(xcore.NewXLanguage),
+		"NewXLanguageFromFile":      reflect.ValueOf(xcore.NewXLanguageFromFile),
+		"NewXLanguageFromString":    reflect.ValueOf(xcore.NewXLanguageFromString),
+		"NewXLanguageFromXMLFile":   reflect.ValueOf(xcore.NewXLanguageFromXMLFile),
+		"NewXLanguageFromXMLString": reflect.ValueOf(xcore.NewXLanguageFromXMLString),
+		"NewXTemplate":              reflect.ValueOf(xcore.NewXTemplate),
+		"NewXTemplateFromFile":      reflect.ValueOf(xcore.NewXTemplateFromFile),
+		"NewXTemplateFromString":    reflect.ValueOf(xcore.NewXTemplateFromString),
+		"VERSION":                   reflect.ValueOf(constant.MakeFromLiteral("\"2.0.4\"", token.STRING, 0)),
+
+		// type definitions
+		"XCache":                reflect.ValueOf((*xcore.XCache)(nil)),
+		"XCacheEntry":           reflect.ValueOf((*xcore.XCacheEntry)(nil)),
+		"XDataset":              reflect.ValueOf((*xcore.XDataset)(nil)),
+		"XDatasetCollection":    reflect.ValueOf((*xcore.XDatasetCollection)(nil)),
+		"XDatasetCollectionDef": reflect.ValueOf((*xcore.XDatasetCollectionDef)(nil)),
+		"XDatasetCollectionTS":  reflect.ValueOf((*xcore.XDatasetCollectionTS)(nil)),
+		"XDatasetDef":           reflect.ValueOf((*xcore.XDatasetDef)(nil)),
+		"XDatasetTS":            reflect.ValueOf((*xcore.XDatasetTS)(nil)),
+		"XLanguage":             reflect.ValueOf((*xcore.XLanguage)(nil)),
+		"XTemplate":             reflect.ValueOf((*xcore.XTemplate)(nil)),
+		"XTemplateData":         reflect.ValueOf((*xcore.XTemplateData)(nil)),
+		"XTemplateParam":        reflect.ValueOf((*xcore.XTemplateParam)(nil)),
+
+		// interface wrapper definitions
+		"_XDatasetCollectionDef": reflect.ValueOf((*_github_com_webability_go_xcore_v2_XDatasetCollectionDef)(nil)),
+		"_XDatasetDef":           reflect.ValueOf((*_github_com_webability_go_xcore_v2_XDatasetDef)(nil)),
+	}
+}
+
+// _github_com_webability_go_xcore_v2_XDatasetCollectionDef is an interface wrapper for XDatasetCollectionDef type
+type _github_com_webability_go_xcore_v2_XDatasetCollectionDef struct {
+	IValue         interface{}
+	WClone         func() xcore.XDatasetCollectionDef
+	WCount         func() int
+	WGet           func(index int) (xcore.XDatasetDef, bool)
+	WGetCollection func(key string) (xcore.XDatasetCollectionDef, bool)
+	WGetData       func(key string) (interface{}, bool)
+	WGetDataBool   func(key string) (bool, bool)
+	WGetDataFloat  func(key string) (float64, bool)
+	WGetDataInt    func(key string) (int, bool)
+	WGetDataString func(key string) (string, bool)
+	WGetDataTime   func(key string) (time.Time, bool)
+	WGoString      func() string
+	WPop           func() xcore.XDatasetDef
+	WPush          func(data xcore.XDatasetDef)
+	WShift         func() xcore.XDatasetDef
+	WString        func() string
+	WUnshift       func(data xcore.XDatasetDef)
+}
+
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Clone() xcore.XDatasetCollectionDef {
+	return W.WClone()
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Count() int { return W.WCount() }
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Get(index int) (xcore.XDatasetDef, bool) {
+	return W.WGet(index)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetCollection(key string) (xcore.XDatasetCollectionDef, bool) {
+	return W.WGetCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetData(key string) (interface{}, bool) {
+	return W.WGetData(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetDataBool(key string) (bool, bool) {
+	return W.WGetDataBool(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetDataFloat(key string) (float64, bool) {
+	return W.WGetDataFloat(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetDataInt(key string) (int, bool) {
+	return W.WGetDataInt(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetDataString(key string) (string, bool) {
+	return W.WGetDataString(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GetDataTime(key string) (time.Time, bool) {
+	return W.WGetDataTime(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) GoString() string {
+	return W.WGoString()
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Pop() xcore.XDatasetDef {
+	return W.WPop()
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Push(data xcore.XDatasetDef) {
+	W.WPush(data)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Shift() xcore.XDatasetDef {
+	return W.WShift()
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) String() string { return W.WString() }
+func (W _github_com_webability_go_xcore_v2_XDatasetCollectionDef) Unshift(data xcore.XDatasetDef) {
+	W.WUnshift(data)
+}
+
+// _github_com_webability_go_xcore_v2_XDatasetDef is an interface wrapper for XDatasetDef type
+type _github_com_webability_go_xcore_v2_XDatasetDef struct {
+	IValue               interface{}
+	WClone               func() xcore.XDatasetDef
+	WDel                 func(key string)
+	WGet                 func(key string) (interface{}, bool)
+	WGetBool             func(key string) (bool, bool)
+	WGetBoolCollection   func(key string) ([]bool, bool)
+	WGetCollection       func(key string) (xcore.XDatasetCollectionDef, bool)
+	WGetDataset          func(key string) (xcore.XDatasetDef, bool)
+	WGetFloat            func(key string) (float64, bool)
+	WGetFloatCollection  func(key string) ([]float64, bool)
+	WGetInt              func(key string) (int, bool)
+	WGetIntCollection    func(key string) ([]int, bool)
+	WGetString           func(key string) (string, bool)
+	WGetStringCollection func(key string) ([]string, bool)
+	WGetTime             func(key string) (time.Time, bool)
+	WGetTimeCollection   func(key string) ([]time.Time, bool)
+	WGoString            func() string
+	WSet                 func(key string, data interface{})
+	WString              func() string
+}
+
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) Clone() xcore.XDatasetDef { return W.WClone() }
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) Del(key string)           { W.WDel(key) }
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) Get(key string) (interface{}, bool) {
+	return W.WGet(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetBool(key string) (bool, bool) {
+	return W.WGetBool(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetBoolCollection(key string) ([]bool, bool) {
+	return W.WGetBoolCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetCollection(key string) (xcore.XDatasetCollectionDef, bool) {
+	return W.WGetCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetDataset(key string) (xcore.XDatasetDef, bool) {
+	return W.WGetDataset(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetFloat(key string) (float64, bool) {
+	return W.WGetFloat(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetFloatCollection(key string) ([]float64, bool) {
+	return W.WGetFloatCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetInt(key string) (int, bool) {
+	return W.WGetInt(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetIntCollection(key string) ([]int, bool) {
+	return W.WGetIntCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetString(key string) (string, bool) {
+	return W.WGetString(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetStringCollection(key string) ([]string, bool) {
+	return W.WGetStringCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetTime(key string) (time.Time, bool) {
+	return W.WGetTime(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GetTimeCollection(key string) ([]time.Time, bool) {
+	return W.WGetTimeCollection(key)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) GoString() string { return W.WGoString() }
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) Set(key string, data interface{}) {
+	W.WSet(key, data)
+}
+func (W _github_com_webability_go_xcore_v2_XDatasetDef) String() string { return W.WString() }
@@ -0,0 +1,53 @@
+// Package interpreter runs the library pages from their GO source with the yaegi interpreter, for the hosts in development mode (compiler.interpreted=yes).
+// The xamboo links it with plugins.SetInterpreter at start, unless the server has already set its own interpreter.
+package interpreter
+
+//go:generate yaegi extract -name interpreter github.com/webability-go/xamboo/assets github.com/webability-go/xcore/v2 github.com/webability-go/xconfig
+
+import (
+	"errors"
+	"go/build"
+	"reflect"
+
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+
+	"github.com/webability-go/xamboo/plugins"
+)
+
+// Symbols are the packages of the server shared with the interpreted pages, so they use the same types as the compiled pages.
+// They are generated by yaegi extract (go generate).
+var Symbols = interp.Exports{}
+
+// Yaegi is the interpreter of the library pages
+type Yaegi struct {
+	// GoPath is the GOPATH where the interpreter searches the sources of the packages imported by the pages that are not into the Symbols
+	GoPath string
+}
+
+// New returns a yaegi interpreter with the GOPATH of the server
+func New() *Yaegi {
+	return &Yaegi{GoPath: build.Default.GOPATH}
+}
+
+// Load interprets the source with a new interpreter, so the previous version of the page is freed by the garbage collector
+func (y *Yaegi) Load(sourcepath string) (plugins.PageFunction, error) {
+	i := interp.New(interp.Options{GoPath: y.GoPath})
+	i.Use(stdlib.Symbols)
+	i.Use(Symbols)
+	if _, err := i.EvalPath(sourcepath); err != nil {
+		return nil, err
+	}
+	v, err := i.Eval("main.Run")
+	if err != nil {
+		return nil, err
+	}
+	if !v.IsValid() || v.Kind() != reflect.Func {
+		return nil, errors.New("the page does not contain a valid standard function Run")
+	}
+	run, ok := v.Interface().(plugins.PageFunction)
+	if !ok {
+		return nil, errors.New("the page does not contain a valid standard function Run")
+	}
+	return run, nil
+}
@@ -0,0 +1,66 @@
+package interpreter
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/plugins"
+)
+
+const testPage = `package main
+
+import (
+	"strings"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xcore/v2"
+)
+
+func Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
+	ctx.Code = 201
+	return strings.ToUpper(ctx.LocalPage)
+}
+`
+
+func TestYaegi(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-interpreter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, source := range map[string]string{
+		"home.go":   testPage,
+		"syntax.go": "package main\n\nfunc Run( {\n",
+		"norun.go":  "package main\n\nfunc Other() {}\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	y := New()
+	for _, name := range []string{"syntax.go", "norun.go", "missing.go"} {
+		if _, err := y.Load(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: interpreted without error", name)
+		}
+	}
+
+	// a library page run by the plugins manager with the interpreter
+	plugins.SetInterpreter(y)
+	defer plugins.SetInterpreter(nil)
+	ctx := &assets.Context{LocalPage: "home", LoggerError: log.New(ioutil.Discard, "", 0)}
+	lib, err := plugins.InterpretLibrary(ctx, xcore.NewXCache("test", 0, 0), filepath.Join(dir, "home.go"), filepath.Join(dir, "home.so"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := lib.Run(ctx, nil, nil, nil); data != "HOME" || ctx.Code != 201 {
+		t.Errorf("interpreted page: got %v and the code %d", data, ctx.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// HookQueue is the number of calls to the call: loggers waiting to be run. When the queue is full, the calls are dropped and counted.
+var HookQueue = 1024
+
+// HookWorkers is the number of goroutines running the calls to the call: loggers
+var HookWorkers = 4
+
+// hookPool is a queue of calls with the goroutines that run them
+type hookPool struct {
+	calls chan func()
+}
+
+// defaultHooks is the pool of the call: loggers, created with HookQueue and HookWorkers on the first call
+var defaultHooks *hookPool
+var hooksOnce sync.Once
+
+// newHookPool creates a queue of size calls, and launches the workers that run them
+func newHookPool(size int, workers int) *hookPool {
+	p := &hookPool{calls: make(chan func(), size)}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for call := range p.calls {
+				call()
+			}
+		}()
+	}
+	return p
+}
+
+// Dispatch queues a call to the function of a call: logger. It never blocks: the call is dropped and counted if the queue is full.
+// A panic of the function is recovered and written into the main errors log.
+func (l *Logger) Dispatch(call func()) {
+	pool := l.hooks
+	if pool == nil {
+		hooksOnce.Do(func() { defaultHooks = newHookPool(HookQueue, HookWorkers) })
+		pool = defaultHooks
+	}
+	id := l.File
+	select {
+	case pool.calls <- func() {
+		defer func() {
+			if r := recover(); r != nil {
+				GetCoreLogger("errors").Println("Panic in the log call", id, r, string(debug.Stack()))
+			}
+		}()
+		call()
+	}:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// callWriter sends each line written into a call: logger to its func(string) function
+type callWriter struct {
+	logger *Logger
+}
+
+func (w callWriter) Write(p []byte) (int, error) {
+	if f, ok := w.logger.Function.(func(string)); ok {
+		line := string(p)
+		w.logger.Dispatch(func() { f(line) })
+	}
+	return len(p), nil
+}
+
+// checkFunction verifies the function of a call: logger against its category:
+// the sys and errors logs need a func(string), the stats log needs a function of the request (not a func(string)), the pages log accepts both.
+func checkFunction(category string, function interface{}) error {
+	if function == nil {
+		return errors.New("the function is not set")
+	}
+	_, line := function.(func(string))
+	switch category {
+	case "sys", "errors":
+		if !line {
+			return errors.New("the function of a " + category + " log must be a func(string)")
+		}
+	case "stats":
+		if line {
+			return errors.New("the function of a stats log must receive the request, not a line")
+		}
+	}
+	return nil
+}
+
+// GetHostFunction returns the function of the call: logger of the host, or nil if the logger is not a call: logger
+func GetHostFunction(id string, cat string) (*Logger, interface{}) {
+	l, ok := Loggers["H["+id+"]["+cat+"]"]
+	if !ok || l.Function == nil {
+		return nil, nil
+	}
+	return l, l.Function
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestCallLogger(t *testing.T) {
+	release := make(chan bool)
+	var called uint64
+	lines := make(chan string, 10)
+	l := &Logger{TypeOfLogger: "call", File: "app.Log", category: "errors", hooks: newHookPool(2, 1)}
+	l.Function = func(line string) {
+		<-release
+		atomic.AddUint64(&called, 1)
+		lines <- line
+	}
+	l.Logger = log.New(callWriter{l}, "", 0)
+
+	// the writes never block, even with a slow function
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		l.Logger.Println("error", i)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("the call: logger blocked the writer")
+	}
+	close(release)
+
+	dropped := atomic.LoadUint64(&l.dropped)
+	if dropped < 7 || dropped > 8 {
+		t.Errorf("dropped: got %d, want 7 or 8", dropped)
+	}
+	for i := uint64(0); i < 10-dropped; i++ {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, "error ") {
+				t.Errorf("line: got %q", line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the function was not called")
+		}
+	}
+}
+
+func TestCheckFunction(t *testing.T) {
+	line := func(string) {}
+	hook := func(*assets.Context) {}
+	if checkFunction("errors", line) != nil || checkFunction("pages", line) != nil || checkFunction("pages", hook) != nil || checkFunction("stats", hook) != nil {
+		t.Error("valid functions refused")
+	}
+	if checkFunction("sys", hook) == nil || checkFunction("stats", line) == nil || checkFunction("pages", nil) == nil {
+		t.Error("invalid functions accepted")
+	}
+	if category("H[developers][stats]") != "stats" {
+		t.Error("category of the logger")
+	}
+}
+
+func TestCoreCallLogger(t *testing.T) {
+	// a call: log out of a host is ignored, the server still starts
+	l := Create("X[sys]", "call:app:Log", assets.Log{}, nil, nil)
+	if l == nil || l.Function != nil {
+		t.Fatal("call: logger of the xamboo not ignored")
+	}
+	l.Logger.Println("discarded")
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestLevelLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	errout := &bytes.Buffer{}
+	Loggers = map[string]*Logger{
+		"H[developers][sys]":    {Logger: log.New(out, "", 0)},
+		"H[developers][errors]": {Logger: log.New(errout, "", 0)},
+	}
+	Levels = map[string]assets.LogLevel{"": assets.LogInfo, "developers": assets.LogWarn}
+	defer func() {
+		Loggers = nil
+		Levels = nil
+	}()
+
+	l := GetHostLevelLogger("developers").With("page=home requestid=abc")
+	l.Info("not written")
+	l.Warn("written")
+	if out.Len() != 0 || errout.String() != "WARN host=developers page=home requestid=abc: written\n" {
+		t.Errorf("host level: got %q and %q", out.String(), errout.String())
+	}
+
+	// a page can raise the verbosity
+	l = l.WithLevel(assets.LogDebug)
+	l.Debugf("value %d", 5)
+	if out.String() != "DEBUG host=developers page=home requestid=abc: value 5\n" {
+		t.Errorf("page level: got %q", out.String())
+	}
+
+	// a nil logger writes nothing
+	var nl *assets.LevelLogger
+	nl.Error("nothing")
+
+	// the missing loggers do not panic
+	if GetCoreLogger("nothing") == nil || GetHostLogger("nohost", "pages") == nil || GetHostHook("nohost", "stats") != nil {
+		t.Error("missing loggers")
+	}
+	if level, err := assets.ParseLogLevel("DEBUG"); err != nil || level != assets.LogDebug {
+		t.Error("parse level:", level, err)
+	}
+	if _, err := assets.ParseLogLevel("verbose"); err == nil || !strings.Contains(err.Error(), "verbose") {
+		t.Error("unknown level accepted")
+	}
+}
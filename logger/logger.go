@@ -1,11 +1,16 @@
 package logger
 
 import (
+	"errors"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	//  "plugin"
 	//  "encoding/json"
 
@@ -17,49 +22,81 @@ import (
 )
 
 type Logger struct {
+	// calls dropped by a call: logger because the queue was full (first field, for the 64 bits alignment of the atomic operations)
+	dropped      uint64
 	TypeOfLogger string
 	File         string
 	Logger       *log.Logger
-	Hook         func(*assets.Context)
+	Hook         func(*assets.Context) // the function of a call: logger if it is a func(*assets.Context)
+	Function     interface{}           // the function of a call: logger: func(string), func(*assets.Context) or func(*stat.RequestStat)
+	Writer       *RotateWriter         // the writer of the file loggers
+	Sink         *NetWriter            // the writer of the syslog:, tcp: and udp: loggers
+	Level        assets.LogLevel
+	// host, application and function of a call: logger, linked by LinkHooks once the applications are loaded
+	host        string
+	category    string
+	application string
+	function    string
+	hooks       *hookPool // the queue of the calls, the default one if nil
 }
 
 var Loggers map[string]*Logger
 
+// Levels are the log levels of the hosts, by host name. The main level is the "" entry.
+// Once the server runs, they are read and changed only with GetLevels and SetLevel.
+var Levels map[string]assets.LogLevel
+var levelsMutex sync.RWMutex
+
 func Start() {
 
 	Loggers = make(map[string]*Logger)
+	Levels = make(map[string]assets.LogLevel)
+	go reopenOnSignal()
 
 	// scan config
 
 	// 1. main loggers
+	level, err := assets.ParseLogLevel(config.Config.Log.Level)
+	if err != nil {
+		log.Fatalln("Error in the main log level", err)
+	}
+	Levels[""] = level
 	id := "X[sys]"
-	Loggers[id] = Create(id, config.Config.Log.Sys, nil, nil)
+	Loggers[id] = Create(id, config.Config.Log.Sys, config.Config.Log, nil, nil)
 	id = "X[errors]"
-	Loggers[id] = Create(id, config.Config.Log.Errors, Loggers["X[sys]"].Logger, nil)
+	Loggers[id] = Create(id, config.Config.Log.Errors, config.Config.Log, Loggers["X[sys]"].Logger, nil)
 
 	// 2. listeners have loggers
 	for _, l := range config.Config.Listeners {
 		id = "L[" + l.Name + "][sys]"
-		Loggers[id] = Create(id, l.Log.Sys, Loggers["X[sys]"].Logger, nil)
+		Loggers[id] = Create(id, l.Log.Sys, l.Log, Loggers["X[sys]"].Logger, nil)
 	}
 
 	// 3. hosts
 	for _, h := range config.Config.Hosts {
+		level, err := assets.ParseLogLevel(h.Log.Level)
+		if err != nil {
+			log.Fatalln("Error in the log level of the host", h.Name, err)
+		}
+		Levels[h.Name] = level
 		id = "H[" + h.Name + "][pages]"
-		Loggers[id] = Create(id, h.Log.Pages, Loggers["X[sys]"].Logger, &h)
+		Loggers[id] = Create(id, h.Log.Pages, h.Log, Loggers["X[sys]"].Logger, &h)
 		id = "H[" + h.Name + "][errors]"
-		Loggers[id] = Create(id, h.Log.Errors, Loggers["X[sys]"].Logger, &h)
+		Loggers[id] = Create(id, h.Log.Errors, h.Log, Loggers["X[sys]"].Logger, &h)
 		id = "H[" + h.Name + "][sys]"
-		Loggers[id] = Create(id, h.Log.Sys, Loggers["X[sys]"].Logger, &h)
+		Loggers[id] = Create(id, h.Log.Sys, h.Log, Loggers["X[sys]"].Logger, &h)
 		id = "H[" + h.Name + "][stats]"
-		Loggers[id] = Create(id, h.Log.Stats, Loggers["X[sys]"].Logger, &h)
+		Loggers[id] = Create(id, h.Log.Stats, h.Log, Loggers["X[sys]"].Logger, &h)
 	}
 }
 
 // Then main xamboo runner
-func Create(id string, typeoflogger string, explain *log.Logger, host *assets.Host) *Logger {
+// The file loggers are rotated and compressed following the rotate, compress and retention parameters of the log settings.
+func Create(id string, typeoflogger string, settings assets.Log, explain *log.Logger, host *assets.Host) *Logger {
 
 	var writer io.Writer
+	var rotatewriter *RotateWriter
+	var sink *NetWriter
 	protocol := typeoflogger
 	file := ""
 	textexplain := "Link Log " + id + " to "
@@ -82,29 +119,52 @@ func Create(id string, typeoflogger string, explain *log.Logger, host *assets.Ho
 
 			textexplain += "file: " + file
 
-			writer, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+			rotatewriter, err = NewRotateWriter(file, settings.Rotate, settings.Compress, settings.Retention)
 			if err != nil {
 				log.Fatalln("Failed to open log file:", id, file, err)
 			}
-		} else if protocol == "call" {
-			// only stat on Host can use this one. Any other will be ignored
-			if host != nil {
-				xlogger := strings.Split(typeoflogger, ":")
-				if len(xlogger) == 3 {
-					textexplain += "call: " + xlogger[1] + "." + xlogger[2]
-
-					plugin := host.Plugins[xlogger[1]]
-					hook, err := plugin.Lookup(xlogger[2])
-					if err != nil {
-						log.Fatalln("Failed to find stat call function:", xlogger[1], xlogger[2], err)
-					} else {
-						l := &Logger{TypeOfLogger: protocol, File: xlogger[1] + "." + xlogger[2], Hook: hook.(func(*assets.Context))}
-						return l
-					}
-				} else {
-					log.Fatalln("Failed to link stat call function:", typeoflogger)
+			writer = rotatewriter
+			if settings.Rotate != "" {
+				textexplain += " (rotate " + settings.Rotate + ")"
+			}
+		} else if protocol == "syslog" || protocol == "tcp" || protocol == "udp" {
+			target := typeoflogger[strings.Index(typeoflogger, ":")+1:]
+			file = target
+
+			textexplain += protocol + ": " + target
+			if protocol == "syslog" {
+				severity := SeverityInfo
+				if strings.HasSuffix(id, "[errors]") {
+					severity = SeverityError
 				}
+				sink, err = NewSyslogWriter(target, severity)
+			} else {
+				sink, err = NewNetWriter(protocol, target)
+			}
+			if err != nil {
+				log.Fatalln("Failed to link log sink:", id, typeoflogger, err)
+			}
+			writer = sink
+		} else if protocol == "call" && host == nil {
+			// only the loggers of the hosts can use this one: the log is ignored, as before
+			log.Println("Warning: the call: logs are only available for the hosts, the log is discarded:", id, typeoflogger)
+			writer = ioutil.Discard
+			textexplain += "discard: (call: is only for the hosts)"
+		} else if protocol == "call" {
+			// the functions are into the applications of the host
+			xlogger := strings.Split(typeoflogger, ":")
+			if len(xlogger) != 3 {
+				log.Fatalln("Failed to link call function:", id, typeoflogger)
+			}
+			textexplain += "call: " + xlogger[1] + "." + xlogger[2]
+			if explain != nil {
+				explain.Println(textexplain)
 			}
+			// The function is linked later with LinkHooks, the applications are not loaded yet
+			// The lines are given to the function as they are written, without prefix nor date
+			l := &Logger{TypeOfLogger: protocol, File: xlogger[1] + "." + xlogger[2], host: host.Name, category: category(id), application: xlogger[1], function: xlogger[2]}
+			l.Logger = log.New(callWriter{l}, "", 0)
+			return l
 		} else {
 			log.Fatalln("Log protocol not known:", protocol)
 		}
@@ -115,23 +175,169 @@ func Create(id string, typeoflogger string, explain *log.Logger, host *assets.Ho
 	}
 
 	nlogger := log.New(writer, id+": ", log.LstdFlags)
-	l := &Logger{TypeOfLogger: protocol, File: file, Logger: nlogger}
+	l := &Logger{TypeOfLogger: protocol, File: file, Logger: nlogger, Writer: rotatewriter, Sink: sink}
 	nlogger.Println("Logger starting...")
 	return l
 }
 
+// Reopen closes and opens again all the file logs, after an external rotation of the files (logrotate)
+func Reopen() {
+	for id, l := range Loggers {
+		if l.Writer == nil {
+			continue
+		}
+		if err := l.Writer.Reopen(); err != nil {
+			log.Println("Failed to reopen log file:", id, l.File, err)
+		}
+	}
+}
+
+// Dropped returns the number of lines dropped by each syslog:, tcp: and udp: logger because the sink was down or too slow,
+// and the number of calls dropped by each call: logger because the queue was full
+func Dropped() map[string]uint64 {
+	dropped := map[string]uint64{}
+	for id, l := range Loggers {
+		if l.Sink != nil {
+			dropped[id] = l.Sink.Dropped()
+		}
+		if l.TypeOfLogger == "call" {
+			dropped[id] = atomic.LoadUint64(&l.dropped)
+		}
+	}
+	return dropped
+}
+
+// reopenOnSignal reopens the file logs on each SIGHUP
+func reopenOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		Reopen()
+		if l := Loggers["X[sys]"]; l != nil && l.Logger != nil {
+			l.Logger.Println("SIGHUP received: log files reopened")
+		}
+	}
+}
+
+// LinkHooks links the call: loggers to the function exported by the application plugin of their host.
+// It must be called once the applications are loaded. lookup returns the function of the application of the host.
+func LinkHooks(lookup func(hostname string, application string, function string) (interface{}, error)) {
+	for id, l := range Loggers {
+		if l.TypeOfLogger != "call" || l.Function != nil {
+			continue
+		}
+		function, err := lookup(l.host, l.application, l.function)
+		if err == nil {
+			err = checkFunction(l.category, function)
+		}
+		if err != nil {
+			log.Fatalln("Failed to find call function:", id, l.application, l.function, err)
+		}
+		l.Function = function
+		if hook, ok := function.(func(*assets.Context)); ok {
+			l.Hook = hook
+		}
+	}
+}
+
+// category returns the category of the id of a logger: H[host][category]
+func category(id string) string {
+	p := strings.LastIndex(id, "[")
+	if p < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(id[p+1:], "]")
+}
+
+// GetCoreLogger returns the main logger of the category. A missing category writes into stderr.
 func GetCoreLogger(cat string) *log.Logger {
-	return Loggers["X["+cat+"]"].Logger
+	return getLogger("X[" + cat + "]")
 }
 
+// GetListenerLogger returns the logger of the listener. A missing listener or category writes into stderr.
 func GetListenerLogger(id string, cat string) *log.Logger {
-	return Loggers["L["+id+"]["+cat+"]"].Logger
+	return getLogger("L[" + id + "][" + cat + "]")
 }
 
+// GetHostLogger returns the logger of the host. A missing host or category writes into stderr.
 func GetHostLogger(id string, cat string) *log.Logger {
-	return Loggers["H["+id+"]["+cat+"]"].Logger
+	return getLogger("H[" + id + "][" + cat + "]")
+}
+
+func getLogger(id string) *log.Logger {
+	l, ok := Loggers[id]
+	if !ok {
+		return log.New(os.Stderr, id+": ", log.LstdFlags)
+	}
+	return l.Logger
+}
+
+// GetCoreLevelLogger returns the main leveled logger: debug and info into the sys log, warn and error into the errors log
+func GetCoreLevelLogger() *assets.LevelLogger {
+	levelsMutex.RLock()
+	level := Levels[""]
+	levelsMutex.RUnlock()
+	return &assets.LevelLogger{
+		Level:       level,
+		Output:      GetCoreLogger("sys"),
+		ErrorOutput: GetCoreLogger("errors"),
+	}
+}
+
+// GetHostLevelLogger returns the leveled logger of the host, with the level of the host: debug and info into the sys log, warn and error into the errors log
+func GetHostLevelLogger(id string) *assets.LevelLogger {
+	levelsMutex.RLock()
+	level, ok := Levels[id]
+	if !ok {
+		level = Levels[""]
+	}
+	levelsMutex.RUnlock()
+	return &assets.LevelLogger{
+		Level:       level,
+		Output:      GetHostLogger(id, "sys"),
+		ErrorOutput: GetHostLogger(id, "errors"),
+		Tags:        "host=" + id,
+	}
+}
+
+// GetLevels returns a copy of the log levels, by host name. The main level is the "" entry.
+func GetLevels() map[string]assets.LogLevel {
+	levelsMutex.RLock()
+	defer levelsMutex.RUnlock()
+	levels := make(map[string]assets.LogLevel, len(Levels))
+	for id, level := range Levels {
+		levels[id] = level
+	}
+	return levels
+}
+
+// SetLevel changes the log level of the host while the server runs ("" is the main level). The new level is used by the next requests.
+func SetLevel(id string, name string) error {
+	level, err := assets.ParseLogLevel(name)
+	if err != nil {
+		return err
+	}
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+	if _, ok := Levels[id]; !ok {
+		return errors.New("Unknown host for the log level: " + id)
+	}
+	Levels[id] = level
+	return nil
+}
+
+// WithRequestID returns a logger that writes into the same output as l, with the id of the request on each line
+func WithRequestID(l *log.Logger, id string) *log.Logger {
+	if l == nil || id == "" {
+		return l
+	}
+	return log.New(l.Writer(), l.Prefix()+"["+id+"] ", l.Flags())
 }
 
 func GetHostHook(id string, cat string) func(*assets.Context) {
-	return Loggers["H["+id+"]["+cat+"]"].Hook
+	l, ok := Loggers["H["+id+"]["+cat+"]"]
+	if !ok {
+		return nil
+	}
+	return l.Hook
 }
@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NetBuffer is the number of lines kept by a network writer while the sink is slow or down
+var NetBuffer = 4096
+
+// NetRetry is the minimum delay between two connection attempts to a sink that is down
+var NetRetry = 5 * time.Second
+
+// NetTimeout is the timeout to connect and to write a line to a sink
+var NetTimeout = 5 * time.Second
+
+// NetWriter is a line-oriented writer to a network sink (tcp, udp, unix socket, syslog).
+// The lines are buffered and sent by a goroutine, so the writer never blocks the server.
+// When the buffer is full or the sink is down, the lines are dropped and counted.
+type NetWriter struct {
+	// first fields, for the 64 bits alignment of the atomic operations
+	sent    uint64
+	dropped uint64
+
+	Network string
+	Address string
+
+	dial  func() (net.Conn, error)
+	frame func([]byte) []byte
+	lines chan []byte
+
+	mutex    sync.Mutex
+	closed   bool
+	finished chan bool
+}
+
+// NewNetWriter creates a writer to the tcp or udp address (host:port).
+func NewNetWriter(network string, address string) (*NetWriter, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, errors.New("Unknown network for the log: " + network)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, err
+	}
+	w := newNetWriter(network, address, func() (net.Conn, error) {
+		return net.DialTimeout(network, address, NetTimeout)
+	})
+	w.frame = func(line []byte) []byte {
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			line = append(line, '\n')
+		}
+		return line
+	}
+	return w, nil
+}
+
+func newNetWriter(network string, address string, dial func() (net.Conn, error)) *NetWriter {
+	w := &NetWriter{
+		Network:  network,
+		Address:  address,
+		dial:     dial,
+		lines:    make(chan []byte, NetBuffer),
+		finished: make(chan bool),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of the line. It never blocks and never fails: the line is dropped if the buffer is full.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		atomic.AddUint64(&w.dropped, 1)
+		return len(p), nil
+	}
+	select {
+	case w.lines <- line:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// run sends the lines to the sink, connecting again when the connection is lost
+func (w *NetWriter) run() {
+	var conn net.Conn
+	var lastdial time.Time
+	for line := range w.lines {
+		if w.frame != nil {
+			line = w.frame(line)
+		}
+		if conn == nil {
+			if time.Since(lastdial) < NetRetry {
+				atomic.AddUint64(&w.dropped, 1)
+				continue
+			}
+			lastdial = time.Now()
+			var err error
+			conn, err = w.dial()
+			if err != nil {
+				conn = nil
+				atomic.AddUint64(&w.dropped, 1)
+				continue
+			}
+		}
+		conn.SetWriteDeadline(time.Now().Add(NetTimeout))
+		if _, err := conn.Write(line); err != nil {
+			conn.Close()
+			conn = nil
+			lastdial = time.Time{}
+			atomic.AddUint64(&w.dropped, 1)
+			continue
+		}
+		atomic.AddUint64(&w.sent, 1)
+	}
+	if conn != nil {
+		conn.Close()
+	}
+	close(w.finished)
+}
+
+// Sent returns the number of lines sent to the sink
+func (w *NetWriter) Sent() uint64 {
+	return atomic.LoadUint64(&w.sent)
+}
+
+// Dropped returns the number of lines dropped because the buffer was full or the sink was down
+func (w *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close sends the buffered lines and closes the connection
+func (w *NetWriter) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.lines)
+	w.mutex.Unlock()
+	<-w.finished
+	return nil
+}
+
+// syslog facilities
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog severities used by the loggers
+const (
+	SeverityError = 3
+	SeverityInfo  = 6
+)
+
+// the local syslog sockets
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// NewSyslogWriter creates a writer to a syslog server, in RFC 3164 format with the tag "xamboo".
+// target is [facility] for the local syslog socket, or tcp://host:port[/facility] or udp://host:port[/facility] for a remote syslog.
+// The default facility is local0.
+func NewSyslogWriter(target string, severity int) (*NetWriter, error) {
+	network := "unix"
+	address := ""
+	if p := strings.Index(target, "://"); p >= 0 {
+		network = target[:p]
+		address = target[p+3:]
+		target = ""
+		if s := strings.Index(address, "/"); s >= 0 {
+			target = address[s+1:]
+			address = address[:s]
+		}
+		if network != "tcp" && network != "udp" {
+			return nil, errors.New("Unknown network for the syslog: " + network)
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, err
+		}
+	}
+	if target == "" {
+		target = "local0"
+	}
+	facility, ok := facilities[target]
+	if !ok {
+		return nil, errors.New("Unknown syslog facility: " + target)
+	}
+	priority := facility*8 + severity
+
+	tag := fmt.Sprintf("xamboo[%d]", os.Getpid())
+	hostname, _ := os.Hostname()
+	var w *NetWriter
+	if network == "unix" {
+		w = newNetWriter(network, address, dialLocalSyslog)
+	} else {
+		w = newNetWriter(network, address, func() (net.Conn, error) {
+			return net.DialTimeout(network, address, NetTimeout)
+		})
+	}
+	w.frame = func(line []byte) []byte {
+		msg := strings.TrimRight(string(line), "\n")
+		var header string
+		if network == "unix" {
+			// the local syslog adds the hostname itself
+			header = fmt.Sprintf("<%d>%s %s: ", priority, time.Now().Format(time.Stamp), tag)
+		} else {
+			header = fmt.Sprintf("<%d>%s %s %s: ", priority, time.Now().Format(time.Stamp), hostname, tag)
+		}
+		if network == "tcp" {
+			// non-transparent framing: one message by line
+			return []byte(header + msg + "\n")
+		}
+		return []byte(header + msg)
+	}
+	return w, nil
+}
+
+// dialLocalSyslog connects to the first local syslog socket found
+func dialLocalSyslog() (net.Conn, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			conn, err := net.DialTimeout(network, path, NetTimeout)
+			if err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("No local syslog socket found")
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bufio"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNetWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewNetWriter("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("line 1\n"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "line 1\n" {
+		t.Errorf("udp line: got %q", buf[:n])
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	// a syslog server stand-in
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			received <- scanner.Text()
+		}
+	}()
+
+	w, err := NewSyslogWriter("tcp://"+listener.Addr().String()+"/local3", SeverityError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("H[developers][errors]: an error\n"))
+	w.Write([]byte("second"))
+
+	// local3 = 19, err = 3: 19*8+3 = 155
+	format := regexp.MustCompile(`^<155>\w{3} [ \d]\d \d\d:\d\d:\d\d \S+ xamboo\[\d+\]: (.*)$`)
+	for _, want := range []string{"H[developers][errors]: an error", "second"} {
+		select {
+		case line := <-received:
+			m := format.FindStringSubmatch(line)
+			if m == nil || m[1] != want {
+				t.Errorf("syslog line: got %q, want message %q", line, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("syslog line not received")
+		}
+	}
+
+	if _, err := NewSyslogWriter("nofacility", SeverityInfo); err == nil {
+		t.Error("unknown facility accepted")
+	}
+}
+
+func TestNetWriterDrops(t *testing.T) {
+	// nobody listens on this address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	buffer := NetBuffer
+	NetBuffer = 2
+	defer func() { NetBuffer = buffer }()
+
+	w, err := NewNetWriter("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if n, err := w.Write([]byte("line\n")); n != 5 || err != nil {
+			t.Fatal("the write must not fail:", n, err)
+		}
+	}
+	w.Close()
+	if w.Dropped() != 10 || w.Sent() != 0 {
+		t.Errorf("dropped: got %d dropped and %d sent, want 10 dropped", w.Dropped(), w.Sent())
+	}
+}
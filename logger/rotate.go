@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotationSuffix matches what follows [file]. into the names of the rotated files: the date of the rotation
+// (daily 2006-01-02, hourly 2006-01-02-15, by size 2006-01-02-150405), the sequence .N and .gz if any
+var rotationSuffix = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-\d{2}(\d{4})?)?(\.\d+)?(\.gz)?$`)
+
+// RotateWriter is the writer of the file logs. It rotates the file by time (daily, hourly) or by size (N MB),
+// compresses the rotated files with gzip and keeps only the last rotated files (retention).
+// The rotated files are named [file].[date] or [file].[date].gz
+type RotateWriter struct {
+	Path      string
+	Period    string // "daily", "hourly" or "" for no time rotation
+	MaxSize   int64  // max size of the file in bytes, 0 for no size rotation
+	Compress  bool
+	Retention int // number of rotated files to keep, 0 = keep them all
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	cleaning sync.Mutex     // the rotated files are compressed and cleaned one rotation at a time
+	pending  sync.WaitGroup // compressions and cleanings not finished
+}
+
+// NewRotateWriter opens the file of the log. rotate is "daily", "hourly", "[N]MB" or empty for no rotation.
+func NewRotateWriter(path string, rotate string, compress bool, retention int) (*RotateWriter, error) {
+	w := &RotateWriter{
+		Path:      path,
+		Compress:  compress,
+		Retention: retention,
+	}
+	rotate = strings.ToLower(strings.TrimSpace(rotate))
+	switch {
+	case rotate == "", rotate == "none":
+	case rotate == "daily", rotate == "hourly":
+		w.Period = rotate
+	case strings.HasSuffix(rotate, "mb"):
+		mb, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(rotate, "mb")))
+		if err != nil || mb <= 0 {
+			return nil, errors.New("Error: the rotate parameter of the log is not valid: " + rotate)
+		}
+		w.MaxSize = int64(mb) * 1024 * 1024
+	default:
+		return nil, errors.New("Error: the rotate parameter of the log is not valid: " + rotate)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the file of the log, the caller must own the lock
+func (w *RotateWriter) open() error {
+	file, err := os.OpenFile(w.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+	w.opened = time.Now()
+	if fi, err := file.Stat(); err == nil {
+		w.size = fi.Size()
+		// a file of a previous period is rotated on the first write
+		if fi.Size() > 0 {
+			w.opened = fi.ModTime()
+		}
+	}
+	return nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.mustRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and opens again the file, after an external rotation of the file (SIGHUP)
+func (w *RotateWriter) Reopen() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file != nil {
+		w.file.Close()
+	}
+	return w.open()
+}
+
+// Close closes the file, and waits for the compression and the cleaning of the rotated files
+func (w *RotateWriter) Close() error {
+	w.mutex.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mutex.Unlock()
+	w.pending.Wait()
+	return err
+}
+
+// mustRotate returns true if the period of the file is over or if the new data would exceed the max size
+func (w *RotateWriter) mustRotate(length int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.MaxSize > 0 && w.size+length > w.MaxSize {
+		return true
+	}
+	now := time.Now()
+	switch w.Period {
+	case "daily":
+		return w.opened.Format("20060102") != now.Format("20060102")
+	case "hourly":
+		return w.opened.Format("2006010215") != now.Format("2006010215")
+	}
+	return false
+}
+
+// rotate renames the actual file, opens a new one, and compresses and cleans the rotated files. The caller must own the lock.
+func (w *RotateWriter) rotate() error {
+	w.file.Close()
+	w.file = nil
+
+	var stamp string
+	switch w.Period {
+	case "daily":
+		stamp = w.opened.Format("2006-01-02")
+	case "hourly":
+		stamp = w.opened.Format("2006-01-02-15")
+	default:
+		stamp = time.Now().Format("2006-01-02-150405")
+	}
+	rotated := w.Path + "." + stamp
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = w.Path + "." + stamp + "." + strconv.Itoa(i)
+	}
+	if err := os.Rename(w.Path, rotated); err != nil {
+		// the log must continue anyway
+		w.open()
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	// compression and cleaning are done out of the lock of the log, but never at the same time for two rotations:
+	// a cleaning must not remove a file being compressed
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.cleaning.Lock()
+		defer w.cleaning.Unlock()
+		if w.Compress {
+			compressFile(rotated)
+		}
+		w.clean()
+	}()
+	return nil
+}
+
+// clean removes the oldest rotated files over the retention.
+// A rotated file and its .gz count as one rotation (the .gz may exist before the file is removed).
+// Only the files named like the rotations are removed, not the other files of the directory that start with the name of the log.
+func (w *RotateWriter) clean() {
+	if w.Retention <= 0 {
+		return
+	}
+	dir := filepath.Dir(w.Path)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	base := filepath.Base(w.Path) + "."
+	rotations := map[string]time.Time{} // name without .gz => modification time
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), base) || !rotationSuffix.MatchString(strings.TrimPrefix(f.Name(), base)) {
+			continue
+		}
+		name := strings.TrimSuffix(f.Name(), ".gz")
+		if t, ok := rotations[name]; !ok || f.ModTime().After(t) {
+			rotations[name] = f.ModTime()
+		}
+	}
+	names := make([]string, 0, len(rotations))
+	for name := range rotations {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if !rotations[names[i]].Equal(rotations[names[j]]) {
+			return rotations[names[i]].Before(rotations[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	for i := 0; i < len(names)-w.Retention; i++ {
+		os.Remove(filepath.Join(dir, names[i]))
+		os.Remove(filepath.Join(dir, names[i]+".gz"))
+	}
+}
+
+// compressFile compresses the file into [file].gz and removes it
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	// keep the date of the log for the retention
+	if fi, err := in.Stat(); err == nil {
+		os.Chtimes(path+".gz", fi.ModTime(), fi.ModTime())
+	}
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
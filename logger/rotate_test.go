@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pages.log")
+
+	if _, err := NewRotateWriter(path, "weekly", false, 0); err == nil {
+		t.Error("An unknown rotate parameter should be refused")
+	}
+	w, err := NewRotateWriter(path, "1MB", false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.MaxSize = 100
+	line := []byte(strings.Repeat("x", 59) + "\n")
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close waits for the cleaning of the rotated files
+	w.Close()
+
+	// 5 lines of 60 bytes with 100 bytes max: 4 rotations, only 2 rotated files kept
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Fatalf("Expected the log and 2 rotated files, got %d files", len(files))
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != string(line) {
+		t.Errorf("The actual log should contain only the last line, got %q", data)
+	}
+}
+
+func TestRotateCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pages.log")
+
+	// quick rotations: the compressions and the cleanings must not remove a file being compressed
+	w, err := NewRotateWriter(path, "1MB", true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.MaxSize = 10
+	for i := 0; i < 20; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	files, _ := ioutil.ReadDir(dir)
+	gz := 0
+	for _, f := range files {
+		switch {
+		case f.Name() == "pages.log":
+		case strings.HasSuffix(f.Name(), ".gz"):
+			gz++
+		default:
+			t.Errorf("rotated file not compressed: %s", f.Name())
+		}
+	}
+	if gz != 3 {
+		t.Errorf("Expected 3 compressed rotated files, got %d", gz)
+	}
+}
+
+func TestCleanRotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pages.log")
+
+	// a rotated file being compressed exists with its .gz: they are one rotation
+	// the other files that start with the name of the log are not rotations
+	for _, name := range []string{"pages.log.2026-10-16", "pages.log.2026-10-17", "pages.log.2026-10-17.gz", "pages.log.2026-10-18.gz", "errors.log.2026-10-01",
+		"pages.log.bak", "pages.logger.conf", "pages.log.2026-10-15.old"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0666)
+	}
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"pages.log.2026-10-16", "pages.log.bak", "pages.logger.conf", "pages.log.2026-10-15.old"} {
+		os.Chtimes(filepath.Join(dir, name), old, old)
+	}
+
+	w := &RotateWriter{Path: path, Retention: 2}
+	w.clean()
+	for name, want := range map[string]bool{
+		"pages.log.2026-10-16":     false,
+		"pages.log.2026-10-17":     true,
+		"pages.log.2026-10-17.gz":  true,
+		"pages.log.2026-10-18.gz":  true,
+		"errors.log.2026-10-01":    true,
+		"pages.log.bak":            true,
+		"pages.logger.conf":        true,
+		"pages.log.2026-10-15.old": true,
+	} {
+		if fileExists(filepath.Join(dir, name)) != want {
+			t.Errorf("%s: exists should be %v", name, want)
+		}
+	}
+}
+
+func TestCompressFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pages.log.2026-10-18")
+	ioutil.WriteFile(path, []byte("a log line\n"), 0666)
+
+	if err := compressFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(path) {
+		t.Error("The rotated file should be removed once compressed")
+	}
+	f, err := os.Open(path + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(gz); string(data) != "a log line\n" {
+		t.Errorf("Wrong compressed data: %q", data)
+	}
+}
@@ -0,0 +1,201 @@
+package xamboo
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/stat"
+)
+
+// MetricsPath is the default path of the metrics endpoint
+const MetricsPath = "/metrics"
+
+// serveMetrics serves the metrics if the request is for the metrics endpoint of the settings.
+// It returns true if the request has been served.
+func serveMetrics(w http.ResponseWriter, r *http.Request, settings assets.Metrics) bool {
+	if !settings.Enabled {
+		return false
+	}
+	path := settings.Path
+	if path == "" {
+		path = MetricsPath
+	}
+	if r.URL.Path != path {
+		return false
+	}
+	if settings.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+settings.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return true
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteMetrics(w)
+	return true
+}
+
+// metricsHandler serves the metrics endpoint of the listener, and any other request with the handler
+func metricsHandler(settings assets.Metrics, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveMetrics(w, r, settings) {
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// WriteMetrics writes all the metrics of the server in Prometheus text format:
+// requests, durations, bytes, compilations, caches, dropped log lines and go runtime.
+func WriteMetrics(out io.Writer) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	m := stat.SystemMetrics.Snapshot()
+
+	header(w, "xamboo_requests_total", "counter", "Requests served, by host, code, method and engine.")
+	keys := make([]stat.RequestKey, 0, len(m.Requests))
+	for k := range m.Requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Engine < b.Engine
+	})
+	for _, k := range keys {
+		sample(w, "xamboo_requests_total", labels("host", k.Host, "code", strconv.Itoa(k.Code), "method", k.Method, "engine", k.Engine), float64(m.Requests[k]))
+	}
+
+	header(w, "xamboo_request_duration_seconds", "histogram", "Duration of the requests, by host.")
+	for _, host := range sortedKeys(m.Durations) {
+		h := m.Durations[host]
+		cumulative := uint64(0)
+		for i, count := range h.Counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(stat.DurationBuckets) {
+				le = formatFloat(stat.DurationBuckets[i])
+			}
+			sample(w, "xamboo_request_duration_seconds_bucket", labels("host", host, "le", le), float64(cumulative))
+		}
+		sample(w, "xamboo_request_duration_seconds_sum", labels("host", host), h.Sum)
+		sample(w, "xamboo_request_duration_seconds_count", labels("host", host), float64(h.Count))
+	}
+
+	header(w, "xamboo_bytes_served_total", "counter", "Bytes sent, after compression, by host.")
+	for _, host := range sortedKeys(m.Bytes) {
+		sample(w, "xamboo_bytes_served_total", labels("host", host), float64(m.Bytes[host]))
+	}
+
+	header(w, "xamboo_requests_in_flight", "gauge", "Requests being served.")
+	sample(w, "xamboo_requests_in_flight", "", float64(m.InFlight))
+
+	b := compiler.GetBuildStats()
+	header(w, "xamboo_builds_total", "counter", "Compilations of the libraries and applications.")
+	sample(w, "xamboo_builds_total", "", float64(b.Builds))
+	header(w, "xamboo_build_failures_total", "counter", "Failed compilations.")
+	sample(w, "xamboo_build_failures_total", "", float64(b.Failures))
+	header(w, "xamboo_build_duration_seconds_total", "counter", "Total duration of the compilations.")
+	sample(w, "xamboo_build_duration_seconds_total", "", b.Duration.Seconds())
+
+	caches := assets.GetCaches()
+	header(w, "xamboo_cache_hits_total", "counter", "Hits of the caches.")
+	for _, c := range caches {
+		sample(w, "xamboo_cache_hits_total", labels("cache", c.ID), float64(c.Hits))
+	}
+	header(w, "xamboo_cache_misses_total", "counter", "Misses of the caches.")
+	for _, c := range caches {
+		sample(w, "xamboo_cache_misses_total", labels("cache", c.ID), float64(c.Misses))
+	}
+	header(w, "xamboo_cache_hit_ratio", "gauge", "Hits over hits and misses of the caches, since the start.")
+	for _, c := range caches {
+		ratio := 0.0
+		if c.Hits+c.Misses > 0 {
+			ratio = float64(c.Hits) / float64(c.Hits+c.Misses)
+		}
+		sample(w, "xamboo_cache_hit_ratio", labels("cache", c.ID), ratio)
+	}
+	header(w, "xamboo_cache_items", "gauge", "Entries into the caches.")
+	for _, c := range caches {
+		sample(w, "xamboo_cache_items", labels("cache", c.ID), float64(c.Items))
+	}
+
+	if stat.SystemStat != nil {
+		dropped := stat.SystemStat.LogsDropped()
+		header(w, "xamboo_log_dropped_total", "counter", "Lines or calls dropped by the network and call: loggers.")
+		for _, id := range sortedKeys(dropped) {
+			sample(w, "xamboo_log_dropped_total", labels("logger", id), float64(dropped[id]))
+		}
+		header(w, "process_start_time_seconds", "gauge", "Start time of the server since unix epoch in seconds.")
+		sample(w, "process_start_time_seconds", "", float64(stat.SystemStat.Start.UnixNano())/float64(time.Second))
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	header(w, "go_goroutines", "gauge", "Number of goroutines.")
+	sample(w, "go_goroutines", "", float64(runtime.NumGoroutine()))
+	header(w, "go_memstats_alloc_bytes", "gauge", "Bytes allocated and still in use.")
+	sample(w, "go_memstats_alloc_bytes", "", float64(mem.Alloc))
+	header(w, "go_memstats_heap_inuse_bytes", "gauge", "Bytes of the heap in use.")
+	sample(w, "go_memstats_heap_inuse_bytes", "", float64(mem.HeapInuse))
+	header(w, "go_memstats_sys_bytes", "gauge", "Bytes obtained from the system.")
+	sample(w, "go_memstats_sys_bytes", "", float64(mem.Sys))
+	header(w, "go_gc_cycles_total", "counter", "Completed GC cycles.")
+	sample(w, "go_gc_cycles_total", "", float64(mem.NumGC))
+}
+
+func header(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sample(w io.Writer, name string, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// labels builds the {name="value",...} of a sample from the pairs name, value
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"=\""+labelEscaper.Replace(pairs[i+1])+"\"")
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch v := m.(type) {
+	case map[string]stat.Histogram:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]uint64:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,56 @@
+package xamboo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestServeMetrics(t *testing.T) {
+	cache := assets.RegisterCache(xcore.NewXCache("metricstest", 0, 0))
+	cache.Set("a", 1)
+	assets.CacheGet(cache, "a")
+	assets.CacheGet(cache, "b")
+
+	settings := assets.Metrics{Enabled: true, Token: "secret"}
+
+	w := httptest.NewRecorder()
+	if serveMetrics(w, httptest.NewRequest("GET", "/page", nil), settings) {
+		t.Fatal("a page served as metrics")
+	}
+
+	w = httptest.NewRecorder()
+	if !serveMetrics(w, httptest.NewRequest("GET", "/metrics", nil), settings) || w.Code != http.StatusUnauthorized {
+		t.Fatal("metrics served without token:", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	if !serveMetrics(w, r, settings) || w.Code != http.StatusOK {
+		t.Fatal("metrics not served:", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE xamboo_requests_total counter\n",
+		"# TYPE xamboo_request_duration_seconds histogram\n",
+		`xamboo_cache_hits_total{cache="metricstest"} 1` + "\n",
+		`xamboo_cache_misses_total{cache="metricstest"} 1` + "\n",
+		`xamboo_cache_hit_ratio{cache="metricstest"} 0.5` + "\n",
+		`xamboo_cache_items{cache="metricstest"} 1` + "\n",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics: %q not found into\n%s", want, body)
+		}
+	}
+
+	if l := labels("page", "a\"b\\c\nd"); l != `{page="a\"b\\c\nd"}` {
+		t.Errorf("labels escaping: got %s", l)
+	}
+}
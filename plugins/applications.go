@@ -0,0 +1,56 @@
+package plugins
+
+import (
+	"errors"
+	"log"
+	"plugin"
+
+	"github.com/webability-go/xconfig"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/compiler"
+)
+
+// LoadApplications loads the application plugins declared into the config of the host, links their Application and starts them for the host.
+// Every application is declared with plugin.[app].library = [path to the .so], and optionally plugin.[app].code = [path to the source]
+// to compile the library when it does not exist or when the source is newer.
+func LoadApplications(host *assets.Host, logger *log.Logger) error {
+	if host.Config == nil {
+		return nil
+	}
+	plugins, _ := host.Config.Get("plugin")
+	if plugins == nil {
+		return nil
+	}
+	host.Plugins = make(map[string]*plugin.Plugin)
+	host.Applications = make(map[string]assets.Application)
+	c_plugins := plugins.(*xconfig.XConfig)
+	for app := range c_plugins.Parameters {
+		plugindata, _ := c_plugins.Get(app)
+		if plugindata == nil {
+			continue
+		}
+		c_plugindata := plugindata.(*xconfig.XConfig)
+
+		library, _ := c_plugindata.GetString("library")
+		code, _ := c_plugindata.GetString("code")
+		if library == "" {
+			return errors.New("Error: the application " + app + " of the host " + host.Name + " has no library")
+		}
+		lib, err := Get(logger, compiler.BuildSettings(host.Config), code, library)
+		if err != nil {
+			return err
+		}
+
+		var application assets.Application
+		err = Lookup(lib.Lib, "Application", &application)
+		if err != nil {
+			return errors.New("Error linking application main interface Application: " + err.Error())
+		}
+
+		host.Plugins[app] = lib.Lib
+		host.Applications[app] = application
+		application.StartHost(*host)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+const buildidSection = ".note.go.buildid"
+const buildidNoteType = 4 // type of the GO build id note
+
+// BuildID returns the GO build id of the compiled file, read from the .note.go.buildid section of the ELF file.
+// If the file is not an ELF file or has no GO build id, it returns a hash of the content of the file, prefixed by "sha256:".
+// Two files with the same build id are the same plugin and cannot be loaded twice.
+func BuildID(path string) (string, error) {
+	f, err := elf.Open(path)
+	if err == nil {
+		id, err := readBuildIDNote(f)
+		f.Close()
+		if err == nil && id != "" {
+			return id, nil
+		}
+	}
+	return contentHash(path)
+}
+
+// readBuildIDNote parses the note: namesz, descsz, type (4 bytes each in the byte order of the file), then the name "Go\0\0" and the build id
+func readBuildIDNote(f *elf.File) (string, error) {
+	s := f.Section(buildidSection)
+	if s == nil {
+		return "", errors.New("no " + buildidSection + " section")
+	}
+	data, err := s.Data()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 16 {
+		return "", errors.New("the " + buildidSection + " section is too short")
+	}
+	namesz := f.ByteOrder.Uint32(data[0:4])
+	descsz := f.ByteOrder.Uint32(data[4:8])
+	notetype := f.ByteOrder.Uint32(data[8:12])
+	// the name is padded to 4 bytes
+	start := 12 + (namesz+3)&^3
+	if notetype != buildidNoteType || uint32(len(data)) < start+descsz || !strings.HasPrefix(string(data[12:12+namesz]), "Go") {
+		return "", errors.New("the " + buildidSection + " section is not a GO build id note")
+	}
+	return string(data[start : start+descsz]), nil
+}
+
+func contentHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
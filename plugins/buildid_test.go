@@ -0,0 +1,34 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildID(t *testing.T) {
+	// the test binary itself is a GO ELF file with a build id
+	id, err := BuildID(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" || strings.HasPrefix(id, "sha256:") {
+		t.Errorf("Expected a GO build id from the ELF note, got %q", id)
+	}
+
+	f, err := ioutil.TempFile("", "buildid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not an ELF file")
+	f.Close()
+	id, err = BuildID(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(id, "sha256:") {
+		t.Errorf("Expected a content hash, got %q", id)
+	}
+}
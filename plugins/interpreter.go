@@ -0,0 +1,88 @@
+package plugins
+
+import (
+	"errors"
+	"os"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// Interpreter runs the library pages from their GO source without compiling them. It is the development mode of the library engine:
+// a change of the source is loaded instantly and does not leave a new .so in memory.
+type Interpreter interface {
+	// Load interprets the source of the page and returns its Run function
+	Load(sourcepath string) (PageFunction, error)
+}
+
+// The xamboo links the yaegi interpreter of the interpreter package at start, unless the server sets another one with SetInterpreter before
+var interpreter Interpreter
+
+var ErrNoInterpreter = errors.New("the interpreted mode is not available, no interpreter has been set with plugins.SetInterpreter")
+
+// SetInterpreter sets the interpreter of the library pages in development mode. It must be called before the server starts.
+func SetInterpreter(i Interpreter) {
+	interpreter = i
+}
+
+// GetInterpreter returns the interpreter of the library pages, nil if none is set
+func GetInterpreter() Interpreter {
+	return interpreter
+}
+
+// Interpreted returns true if the library pages of the host must be interpreted (compiler.interpreted=yes into the host config).
+func Interpreted(settings *assets.Build) bool {
+	return settings != nil && settings.Interpreted
+}
+
+// OutOfProcess returns true if the library pages of the host must be run by worker processes (compiler.process=yes into the host config).
+func OutOfProcess(settings *assets.Build) bool {
+	return settings != nil && settings.Process
+}
+
+// InterpretLibrary returns the plugin of a library page with its Run function interpreted from the source.
+// The source is interpreted again each time it changes. The plugin has no GO library (Lib is nil) and is never compiled.
+func InterpretLibrary(ctx *assets.Context, cache *xcore.XCache, sourcepath string, pluginpath string) (*assets.Plugin, error) {
+
+	var lib *assets.Plugin
+	cdata, _ := assets.CacheGet(cache, sourcepath)
+	if cdata != nil {
+		lib = cdata.(*assets.Plugin)
+	} else {
+		lib = newPlugin(nil, sourcepath, pluginpath)
+		cache.Set(sourcepath, lib)
+	}
+
+	lib.Lock()
+	defer lib.Unlock()
+
+	fi, err := os.Stat(sourcepath)
+	if err != nil {
+		return nil, fail(ctx.LoggerError, lib, "Error: "+sourcepath+" Source file does not exists.\n")
+	}
+	if !fi.ModTime().After(lib.LastBuild) {
+		if lib.Status == 1 {
+			return lib, nil
+		}
+		if lib.LastError != nil {
+			return nil, lib.LastError
+		}
+	}
+
+	if interpreter == nil {
+		lib.LastError = ErrNoInterpreter
+		return nil, fail(ctx.LoggerError, lib, "Error: "+ErrNoInterpreter.Error()+"\n")
+	}
+	lib.LastBuild = fi.ModTime()
+	run, err := interpreter.Load(sourcepath)
+	if err != nil {
+		lib.LastError = err
+		return nil, fail(ctx.LoggerError, lib, "Error: the source could not be interpreted "+sourcepath+"\n"+err.Error()+"\n")
+	}
+	lib.LastError = nil
+	lib.Messages += "Interpreted: " + sourcepath + "\n"
+	lib.Run = run
+	lib.Status = 1
+	return lib, nil
+}
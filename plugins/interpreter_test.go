@@ -0,0 +1,75 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+type testInterpreter struct {
+	loads int
+}
+
+func (i *testInterpreter) Load(sourcepath string) (PageFunction, error) {
+	i.loads++
+	loads := i.loads
+	return func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{} {
+		return loads
+	}, nil
+}
+
+func TestInterpretLibrary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-interpreter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "home.go")
+	if err = ioutil.WriteFile(source, []byte("package main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &assets.Context{LoggerError: log.New(ioutil.Discard, "", 0)}
+	defer SetInterpreter(nil)
+
+	// without interpreter
+	SetInterpreter(nil)
+	cache := xcore.NewXCache("interpretertest", 0, 0)
+	if _, err = InterpretLibrary(ctx, cache, source, filepath.Join(dir, "home.so")); err == nil {
+		t.Fatal("page interpreted without interpreter")
+	}
+	data, _ := cache.Get(source)
+	if lib := data.(*assets.Plugin); lib.LastError != ErrNoInterpreter || lib.Status != 2 {
+		t.Errorf("plugin without interpreter: got %v, status %d", lib.LastError, lib.Status)
+	}
+
+	// with an interpreter, the page is interpreted again only when the source changes
+	i := &testInterpreter{}
+	SetInterpreter(i)
+	cache = xcore.NewXCache("interpretertest2", 0, 0)
+	pluginpath := filepath.Join(dir, "home2.so")
+	for n := 0; n < 2; n++ {
+		lib, err := InterpretLibrary(ctx, cache, source, pluginpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lib.Run(ctx, nil, nil, nil); got != 1 {
+			t.Errorf("run %d: got version %v, want 1", n, got)
+		}
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(source, later, later)
+	lib, err := InterpretLibrary(ctx, cache, source, pluginpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lib.Run(ctx, nil, nil, nil); got != 2 || i.loads != 2 {
+		t.Errorf("changed source: got version %v, %d loads", got, i.loads)
+	}
+}
@@ -0,0 +1,121 @@
+// plugins is the code charged to compile and load the GO plugins (.so libraries) used by the xamboo, and to link their functions.
+// Every plugin goes through it: engines, applications, stat hooks and library pages (library and wajafapp engines).
+package plugins
+
+import (
+	"errors"
+	"plugin"
+	"strings"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/workers"
+)
+
+// PageFunction is the standard function exported by the library pages (Run, or any other function for wajafapp pages)
+type PageFunction = func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{}
+
+// GetLibrary returns the plugin of a library page, compiled and loaded, with its Run function linked.
+// The cache of the engine keeps the *assets.Plugin by source path. If the plugin is not known yet, it is created
+// from the last compiled version on disk with the compiler settings of the host (ctx.Sysparams), and registered into the compiler supervisor.
+func GetLibrary(ctx *assets.Context, cache *xcore.XCache, sourcepath string, pluginpath string) (*assets.Plugin, error) {
+
+	var lib *assets.Plugin
+
+	// If the plugin is not loaded, load it (equivalent of cache for other types of server)
+	// verify if the code is loaded in memory
+	cdata, _ := assets.CacheGet(cache, sourcepath)
+	if cdata != nil {
+		lib = cdata.(*assets.Plugin)
+	} else {
+		lib = newPlugin(pluginSettings(compiler.BuildSettings(ctx.Sysparams), false), sourcepath, pluginpath)
+		cache.Set(sourcepath, lib)
+		// the supervisor will recompile it in background when the source changes
+		compiler.Register(lib, ctx.LoggerError)
+	}
+
+	if loaded(lib) {
+		// loaded: any change of the source is compiled by the supervisor, the actual version serves meanwhile
+		return lib, nil
+	}
+
+	// the lock of the plugin is not held during the compilation
+	if err := load(ctx.LoggerError, lib); err != nil {
+		return nil, err
+	}
+	lib.Lock()
+	defer lib.Unlock()
+	if lib.Status == 1 {
+		// linked by another request meanwhile
+		return lib, nil
+	}
+	run, err := LookupPageFunction(lib.Lib, "Run")
+	if err != nil {
+		return nil, fail(ctx.LoggerError, lib, "Error: "+err.Error()+" "+lib.SourcePath+"\n")
+	}
+	lib.Run = run
+	lib.Status = 1
+	return lib, nil
+}
+
+// GetProcessLibrary returns the library page compiled as a standalone binary [page].bin.[version], with its Run function linked to the worker process
+// that runs it. The binary is recompiled by the compiler supervisor like a plugin, and the new binary replaces the worker on the next hit.
+func GetProcessLibrary(ctx *assets.Context, cache *xcore.XCache, sourcepath string, pluginpath string) (*assets.Plugin, error) {
+
+	var lib *assets.Plugin
+	cdata, _ := assets.CacheGet(cache, sourcepath)
+	if cdata != nil {
+		lib = cdata.(*assets.Plugin)
+	} else {
+		lib = newPlugin(pluginSettings(compiler.BuildSettings(ctx.Sysparams), true), sourcepath, strings.TrimSuffix(pluginpath, ".so")+".bin")
+		cache.Set(sourcepath, lib)
+		compiler.Register(lib, ctx.LoggerError)
+	}
+
+	if loaded(lib) {
+		return lib, nil
+	}
+	if err := load(ctx.LoggerError, lib); err != nil {
+		return nil, err
+	}
+	lib.Lock()
+	defer lib.Unlock()
+	if lib.Status == 1 {
+		return lib, nil
+	}
+	lib.Run = workers.Get(sourcepath, lib.PluginVPath, ctx.Sysparams, ctx.LoggerError).Run
+	lib.Status = 1
+	return lib, nil
+}
+
+// pluginSettings returns a copy of the settings of the compiler for a plugin or a worker binary
+func pluginSettings(settings *assets.Build, process bool) *assets.Build {
+	if settings == nil {
+		if !process {
+			return nil
+		}
+		settings = &assets.Build{}
+	}
+	s := *settings
+	s.Interpreted = false
+	s.Process = process
+	return &s
+}
+
+// LookupPageFunction searches the exported function into the plugin and verifies it is a standard page function
+func LookupPageFunction(lib *plugin.Plugin, name string) (PageFunction, error) {
+	if lib == nil {
+		return nil, errors.New("the library is not loaded")
+	}
+	fct, err := lib.Lookup(name)
+	if err != nil {
+		return nil, errors.New("the called library does not contain a function " + name + ": " + err.Error())
+	}
+	xfct, ok := fct.(PageFunction)
+	if !ok {
+		return nil, errors.New("the called library does not contain a valid standard function " + name)
+	}
+	return xfct, nil
+}
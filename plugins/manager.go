@@ -0,0 +1,265 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plugin"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/compiler"
+)
+
+// The manager keeps every plugin loaded by the xamboo: engines, applications and library pages.
+// A plugin may have a source (a .go file or a package directory): it is then compiled into [plugin].so.[version] when
+// there is no compiled version or when the source is newer. Without source, the .so file is loaded as is.
+
+type manager struct {
+	mutex   sync.Mutex
+	plugins map[string]*assets.Plugin // by plugin path
+}
+
+var registry = &manager{
+	plugins: map[string]*assets.Plugin{},
+}
+
+// newPlugin returns the plugin of the registry, or creates it from the last compiled version on disk with the settings of the compiler
+func newPlugin(settings *assets.Build, sourcepath string, pluginpath string) *assets.Plugin {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if lib, ok := registry.plugins[pluginpath]; ok {
+		return lib
+	}
+	lib := &assets.Plugin{
+		SourcePath:  sourcepath,
+		PluginPath:  pluginpath,
+		PluginVPath: pluginpath,
+		Version:     0,
+		Messages:    "",
+		Status:      0, // 0 = must compile or/and load (first creation of library)
+		Libs:        map[string]*plugin.Plugin{},
+		Build:       settings,
+	}
+	if sourcepath != "" {
+		// continue with the last compiled version on disk (if any), the compiler will create the next one (1 at first compile)
+		lib.Version = compiler.LastVersion(pluginpath)
+		lib.PluginVPath = pluginpath + ".1"
+		if lib.Version > 0 {
+			lib.PluginVPath = pluginpath + "." + strconv.Itoa(lib.Version)
+		}
+	}
+	registry.plugins[pluginpath] = lib
+	return lib
+}
+
+// sourceTime returns the modification time of the source. For a package directory, it is the last modified .go file into it.
+func sourceTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !fi.IsDir() {
+		return fi.ModTime(), nil
+	}
+	last := fi.ModTime()
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(p, ".go") && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+		return nil
+	})
+	return last, nil
+}
+
+// load compiles the plugin if needed and opens it (a worker binary is only compiled). The functions are not linked and the status stays 0 if everything is ok.
+// The caller must not own the lock of the plugin: it is released during the compilation, so the status can be read meanwhile.
+func load(logger *log.Logger, lib *assets.Plugin) error {
+
+	lib.Lock()
+	defer lib.Unlock()
+	if lib.Status == 1 {
+		// loaded by another request
+		return nil
+	}
+
+	if lib.SourcePath != "" {
+		stime, err := sourceTime(lib.SourcePath)
+		if err != nil {
+			if lib.Status != 2 {
+				fail(logger, lib, "Error: "+lib.SourcePath+" Source file does not exists.\n")
+			}
+			return errors.New(lib.Messages)
+		}
+		if lib.Status == 2 && !lib.LastBuild.IsZero() && !stime.After(lib.LastBuild) {
+			// the source did not change since the last failed compilation
+			if lib.LastError != nil {
+				return lib.LastError
+			}
+			return errors.New(lib.Messages)
+		}
+
+		mustcompile := true
+		if dp, err := os.Stat(lib.PluginVPath); err == nil && !stime.After(dp.ModTime()) {
+			mustcompile = false
+		}
+		if mustcompile {
+			lib.Status = 0
+			lib.Unlock()
+			err := compiler.PleaseCompile(&assets.Context{LoggerError: logger}, lib)
+			lib.Lock()
+			if err != nil {
+				// the compiler already logged and kept the messages
+				lib.Status = 2
+				lib.LastError = err
+				return err
+			}
+			lib.LastError = nil
+			if lib.Status == 1 {
+				// loaded by another request during the compilation
+				return nil
+			}
+		}
+	}
+
+	if lib.Build != nil && lib.Build.Process {
+		// a binary run by a worker process, not a plugin
+		lib.Status = 0
+		return nil
+	}
+	if err := Open(lib); err != nil {
+		return fail(logger, lib, "Error: the library .so could not load "+lib.PluginVPath+"\n"+err.Error())
+	}
+	lib.Status = 0
+	return nil
+}
+
+// fail marks the plugin with an error and returns all its messages as the error. The caller owns the lock of the plugin.
+func fail(logger *log.Logger, lib *assets.Plugin, errortext string) error {
+	lib.Status = 2
+	if logger != nil {
+		logger.Println(errortext)
+	}
+	lib.Messages += errortext
+	return errors.New(lib.Messages)
+}
+
+// Get returns the plugin compiled and loaded. It is used for the engines and applications plugins, that are loaded only once at start.
+// If sourcepath is empty, pluginpath must be an already compiled plugin. If not, the source is compiled into pluginpath.[version] if needed.
+// The logger receives the errors and compiler messages. settings may be nil for a default go build.
+func Get(logger *log.Logger, settings *assets.Build, sourcepath string, pluginpath string) (*assets.Plugin, error) {
+	lib := newPlugin(settings, sourcepath, pluginpath)
+	if loaded(lib) {
+		return lib, nil
+	}
+	if err := load(logger, lib); err != nil {
+		return nil, err
+	}
+	lib.Lock()
+	lib.Status = 1
+	lib.Unlock()
+	return lib, nil
+}
+
+// loaded returns true if the plugin is loaded and linked
+func loaded(lib *assets.Plugin) bool {
+	lib.Lock()
+	defer lib.Unlock()
+	return lib.Status == 1
+}
+
+// Open loads the actual compiled version of the plugin (PluginVPath).
+// If a file with the same build id has already been loaded, it is reused since GO cannot load the same plugin twice.
+// The caller must own the lock of the plugin.
+func Open(lib *assets.Plugin) error {
+	// Get GO BuildID to compare with in-memory GO BuildID and keep it with the library itself
+	// if already exists in memory, set it as default, or load it
+	buildid, err := BuildID(lib.PluginVPath)
+	if err != nil {
+		return err
+	}
+	if lib.Libs == nil {
+		lib.Libs = map[string]*plugin.Plugin{}
+	}
+	if plg := lib.Libs[buildid]; plg != nil { // already exists and loaded
+		lib.Lib = plg
+		return nil
+	}
+	plg, err := plugin.Open(lib.PluginVPath)
+	if err != nil {
+		return err
+	}
+	lib.Libs[buildid] = plg
+	lib.Lib = plg
+	return nil
+}
+
+// Lookup searches the exported symbol into the loaded plugin and puts it into target, that must be a pointer to a variable of the expected type.
+// The exported variables of a plugin are pointers, so the pointed value is used if the symbol itself is not of the expected type.
+//
+//	var engine assets.Engine
+//	err := plugins.Lookup(lib.Lib, "Engine", &engine)
+func Lookup(lib *plugin.Plugin, name string, target interface{}) error {
+	if lib == nil {
+		return errors.New("the library is not loaded")
+	}
+	symbol, err := lib.Lookup(name)
+	if err != nil {
+		return errors.New("the library does not contain the symbol " + name + ": " + err.Error())
+	}
+	return setSymbol(name, symbol, target)
+}
+
+// setSymbol puts the symbol, or the value it points to, into target
+func setSymbol(name string, symbol plugin.Symbol, target interface{}) error {
+	tv := reflect.ValueOf(target)
+	if tv.Kind() != reflect.Ptr || tv.IsNil() {
+		return errors.New("the target of the symbol " + name + " must be a pointer")
+	}
+	tv = tv.Elem()
+
+	sv := reflect.ValueOf(symbol)
+	if sv.Type().AssignableTo(tv.Type()) {
+		tv.Set(sv)
+		return nil
+	}
+	if sv.Kind() == reflect.Ptr && !sv.IsNil() && sv.Elem().Type().AssignableTo(tv.Type()) {
+		tv.Set(sv.Elem())
+		return nil
+	}
+	return fmt.Errorf("the symbol %s of the library is of type %s, %s expected", name, sv.Type(), tv.Type())
+}
+
+// GetStatus returns the status of all the plugins known by the manager, ordered by plugin path
+func GetStatus() []compiler.PluginStatus {
+	registry.mutex.Lock()
+	list := make([]compiler.PluginStatus, 0, len(registry.plugins))
+	for _, lib := range registry.plugins {
+		lib.Lock()
+		var diagnostics []compiler.Diagnostic
+		if cerr, ok := lib.LastError.(*compiler.Error); ok {
+			diagnostics = cerr.Diagnostics
+		}
+		list = append(list, compiler.PluginStatus{
+			SourcePath:    lib.SourcePath,
+			PluginPath:    lib.PluginVPath,
+			Version:       lib.Version,
+			Status:        lib.Status,
+			LastBuild:     lib.LastBuild,
+			BuildDuration: lib.BuildDuration,
+			Messages:      lib.Messages,
+			Diagnostics:   diagnostics,
+		})
+		lib.Unlock()
+	}
+	registry.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].PluginPath < list[j].PluginPath })
+	return list
+}
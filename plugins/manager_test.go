@@ -0,0 +1,113 @@
+package plugins
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+type testEngine struct{}
+
+func (e *testEngine) NeedInstance() bool { return false }
+func (e *testEngine) GetInstance(Hostname string, PagesDir string, P string, i assets.Identity) assets.EngineInstance {
+	return nil
+}
+func (e *testEngine) Run(ctx *assets.Context, s interface{}) interface{} { return nil }
+
+func TestLookup(t *testing.T) {
+	var engine assets.Engine
+	if err := Lookup(nil, "Engine", &engine); err == nil {
+		t.Error("symbol found into a library not loaded")
+	}
+	if err := Lookup(&plugin.Plugin{}, "Engine", &engine); err == nil || !strings.Contains(err.Error(), "does not contain the symbol Engine") {
+		t.Errorf("missing symbol: got %v", err)
+	}
+
+	// the exported variables of a plugin are pointers, the functions are values
+	variable := assets.Engine(&testEngine{})
+	function := func() string { return "called" }
+	var run func() string
+	var count int
+	for _, test := range []struct {
+		name   string
+		symbol plugin.Symbol
+		target interface{}
+		ok     bool
+	}{
+		{"variable", &variable, &engine, true},
+		{"value", variable, &engine, true},
+		{"function", function, &run, true},
+		{"wrong type", &variable, &count, false},
+		{"not a pointer", &variable, engine, false},
+		{"nil pointer", &variable, (*assets.Engine)(nil), false},
+	} {
+		engine, run, count = nil, nil, 0
+		err := setSymbol("Symbol", test.symbol, test.target)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got the error %v", test.name, err)
+		}
+	}
+	engine = nil
+	if err := setSymbol("Engine", &variable, &engine); err != nil || engine != variable {
+		t.Errorf("variable: got %v and the error %v", engine, err)
+	}
+	if err := setSymbol("Run", function, &run); err != nil || run() != "called" {
+		t.Errorf("function: got the error %v", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := &bytes.Buffer{}
+	logger := log.New(out, "", 0)
+
+	// the source does not exist: the error is logged only once
+	for i := 0; i < 2; i++ {
+		if _, err := Get(logger, nil, filepath.Join(dir, "missing.go"), filepath.Join(dir, "missing.so")); err == nil || !strings.Contains(err.Error(), "does not exists") {
+			t.Errorf("missing source: got %v", err)
+		}
+	}
+	if n := strings.Count(out.String(), "does not exists"); n != 1 {
+		t.Errorf("missing source: logged %d times", n)
+	}
+
+	// without source, the .so must exist
+	if _, err := Get(logger, nil, "", filepath.Join(dir, "nosource.so")); err == nil {
+		t.Error("missing plugin without source loaded")
+	}
+
+	// the last compiled version is up to date: it is used without compilation (a worker binary is not opened)
+	source := filepath.Join(dir, "worker.go")
+	binary := filepath.Join(dir, "worker")
+	old := time.Now().Add(-time.Hour)
+	for _, file := range []string{source, binary + ".1", binary + ".2"} {
+		if err := ioutil.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(source, old, old); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := Get(logger, &assets.Build{Process: true}, source, binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lib.Status != 1 || lib.Version != 2 || lib.PluginVPath != binary+".2" {
+		t.Errorf("up to date plugin: got the status %d, the version %d and the path %s", lib.Status, lib.Version, lib.PluginVPath)
+	}
+	if again, err := Get(logger, nil, source, binary); err != nil || again != lib {
+		t.Errorf("loaded plugin: got %p and the error %v, want %p", again, err, lib)
+	}
+}
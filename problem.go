@@ -0,0 +1,117 @@
+package xamboo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/webability-go/xamboo/compiler"
+)
+
+// The errors of the main page can be sent as RFC 7807 application/problem+json bodies instead of the error page, for the API hosts.
+// The errorformat parameter of the host config, or of the .page, is one of:
+//   - html: the error page of the host (default)
+//   - problem: always a problem+json body
+//   - auto: a problem+json body if the client prefers JSON to HTML (Accept header)
+//
+// The errors of the inner blocks always use the errorblock of the host: a block is only a part of the body of the calling page.
+
+// Problem is the RFC 7807 body of an error
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Page      string `json:"page"`
+	RequestID string `json:"requestid,omitempty"`
+
+	// only in debug mode
+	Diagnostics []compiler.Diagnostic `json:"diagnostics,omitempty"`
+	Stack       string                `json:"stack,omitempty"`
+	Resolution  []string              `json:"resolution,omitempty"`
+}
+
+// errorFormat returns the format of the errors of the main page: the parameter of the main .page if it is known, else of the host
+func (s *Server) errorFormat() string {
+	if s.MainContext != nil && s.MainContext.MainPageparams != nil {
+		if format, _ := s.MainContext.MainPageparams.GetString("errorformat"); format != "" {
+			return format
+		}
+	}
+	format, _ := s.Host.Config.GetString("errorformat")
+	return format
+}
+
+// wantsProblem returns true if the error of the main page must be sent as a problem+json body
+func (s *Server) wantsProblem() bool {
+	switch s.errorFormat() {
+	case "problem":
+		return true
+	case "auto":
+		return prefersJSON(s.reader.Header.Get("Accept"))
+	}
+	return false
+}
+
+// prefersJSON returns true if the Accept header gives a better quality to JSON (application/problem+json or application/json) than to HTML.
+// On a tie (for instance */*), HTML wins.
+func prefersJSON(accept string) bool {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = v
+				}
+			}
+		}
+		if quality > q[mime] {
+			q[mime] = quality
+		}
+	}
+	quality := func(mimes ...string) float64 {
+		for _, mime := range mimes {
+			if v, ok := q[mime]; ok {
+				return v
+			}
+		}
+		return 0
+	}
+	qjson := quality("application/problem+json", "application/json", "application/*", "*/*")
+	qhtml := quality("text/html", "text/*", "*/*")
+	return qjson > qhtml
+}
+
+// renderProblem returns the problem+json body of the error of the main page
+func (s *Server) renderProblem(page string, code int, err error) string {
+	problem := &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    err.Error(),
+		Instance:  s.reader.URL.Path,
+		Page:      page,
+		RequestID: s.RequestID,
+	}
+	if s.Host.Debug {
+		var cerr *compiler.Error
+		if errors.As(err, &cerr) {
+			problem.Diagnostics = cerr.Diagnostics
+		}
+		var perr *PanicError
+		if errors.As(err, &perr) {
+			problem.Stack = string(perr.Stack)
+		}
+		problem.Resolution = s.resolution
+	}
+	s.writer.Header().Set("Content-Type", "application/problem+json")
+	data, _ := json.Marshal(problem)
+	return string(data)
+}
@@ -0,0 +1,66 @@
+package xamboo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestPrefersJSON(t *testing.T) {
+	tests := map[string]bool{
+		"":                             false,
+		"*/*":                          false,
+		"application/json":             true,
+		"application/problem+json":     true,
+		"application/json, text/plain": true,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": false,
+		"text/html;q=0.5, application/json":                               true,
+		"application/json;q=0.4, */*;q=0.5":                               false,
+		"application/*, text/html;q=0.9":                                  true,
+	}
+	for accept, expected := range tests {
+		if prefersJSON(accept) != expected {
+			t.Errorf("prefersJSON(%q) should be %v", accept, expected)
+		}
+	}
+}
+
+func TestProblemFormat(t *testing.T) {
+	host, dir, cleanup := testHost(t, testEngine{
+		"failed": func(ctx *assets.Context, e interface{}) interface{} {
+			ctx.Code = http.StatusBadRequest
+			return errors.New("the page failed")
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return "main " + assets.EngineWrapperString(e, "failed", nil, "", "", "")
+		},
+	}, map[string]string{"failed": "", "main": ""})
+	defer cleanup()
+	host.Config.Set("errorformat", "auto")
+
+	request := func(page string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/"+page, nil)
+		r.Header.Set("Accept", "application/json")
+		return testRequest(host, dir, r)
+	}
+
+	w := request("failed")
+	problem := Problem{}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" || problem.Status != http.StatusBadRequest || problem.Detail != "the page failed" {
+		t.Errorf("main page: got the code %d, the content type %q and %+v", w.Code, w.Header().Get("Content-Type"), problem)
+	}
+
+	// the inner blocks keep the errorblock of the host
+	w = request("main")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "main ") || !strings.Contains(w.Body.String(), "errorblock") {
+		t.Errorf("inner block: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+}
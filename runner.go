@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -16,7 +17,9 @@ import (
 	"github.com/webability-go/xamboo/assets"
 	"github.com/webability-go/xamboo/compiler"
 	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/interpreter"
 	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/plugins"
 	"github.com/webability-go/xamboo/stat"
 	"github.com/webability-go/xamboo/utils"
 )
@@ -41,7 +44,7 @@ func (cw *CoreWriter) CreateGZiper() {
 	// If there is still not gzip writer, we create one based on cw WRITER
 	// Get a Writer from the Pool
 	gz := zippers.Get().(*gzip.Writer)
-	gz.Reset(cw.ResponseWriter)
+	gz.Reset(zcounter{cw})
 	cw.GZipWriter = gz
 	cw.GZip = true
 }
@@ -66,6 +69,17 @@ func (cw *CoreWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// zcounter counts the bytes written by the gzip writer, after compression
+type zcounter struct {
+	cw *CoreWriter
+}
+
+func (z zcounter) Write(b []byte) (int, error) {
+	n, err := z.cw.ResponseWriter.Write(b)
+	z.cw.zlength += n
+	return n, err
+}
+
 // Makes the hijack function visible for gorilla websockets
 func (cw *CoreWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hj, ok := cw.ResponseWriter.(http.Hijacker); ok {
@@ -78,13 +92,21 @@ func StatLoggerWrapper(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := stat.CreateRequestStat(r.Host+r.URL.Path, r.Method, r.Proto, 0, 0, 0, r.RemoteAddr)
 
+		id := req.SetRequestID(r.Header.Get("X-Request-ID"))
+		w.Header().Set("X-Request-ID", id)
+
+		user, _, _ := r.BasicAuth()
+		req.UpdateClient(r.RequestURI, r.Referer(), r.UserAgent(), user)
+
 		cw := CoreWriter{ResponseWriter: w, RequestStat: req}
 
 		handler.ServeHTTP(&cw, r)
 		if cw.GZip {
 			// IF a gzip writer has been declared during the write, then we close it and put it back to the pool
-			defer cw.GZipWriter.Close()
-			defer zippers.Put(cw.GZipWriter)
+			// It is closed before the stat is ended so the compressed length is complete
+			cw.GZipWriter.Close()
+			zippers.Put(cw.GZipWriter)
+			req.UpdateCompression(cw.zlength)
 		}
 
 		req.UpdateStat(cw.status, cw.length)
@@ -121,7 +143,7 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 	if listenerdef != nil {
 		cw, ok := w.(*CoreWriter)
 		if ok && cw.RequestStat != nil {
-			cw.RequestStat.Hostname = hostdef.Name
+			cw.RequestStat.UpdateHostname(hostdef.Name)
 		} else {
 			fmt.Println("ERROR DETECTED: the writer is not a CoreWriter or the RequestStat is not set (and that should not happen)", r, w)
 			http.Error(w, "Writer error", http.StatusInternalServerError)
@@ -139,6 +161,15 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
+
+		// metrics endpoint and admin API of the host, after the redirect so the token is never accepted over http on a https host
+		if serveMetrics(w, r, hostdef.Metrics) {
+			return
+		}
+		if serveAdmin(w, r, hostdef.Admin) {
+			return
+		}
+
 		// check AUTH
 		if hostdef.Auth.Enabled {
 			user, pass, ok := r.BasicAuth()
@@ -215,6 +246,7 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 			Code:          http.StatusOK,
 			Recursivity:   map[string]int{},
 			GZipCandidate: gzipcandidate,
+			RequestID:     cw.RequestStat.RequestID,
 		}
 		server.Start(w, r)
 	} else {
@@ -223,7 +255,7 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func Run(file string) error {
+func start(file string) error {
 
 	// Load the language if needed for messages
 
@@ -231,6 +263,11 @@ func Run(file string) error {
 	assets.EngineWrapper = wrapper
 	assets.EngineWrapperString = wrapperstring
 
+	// Link the interpreter of the library pages in development mode, if the server has not set its own
+	if plugins.GetInterpreter() == nil {
+		plugins.SetInterpreter(interpreter.New())
+	}
+
 	// Load the config
 	err := config.Config.Load(file)
 	if err != nil {
@@ -242,14 +279,95 @@ func Run(file string) error {
 	logger.Start()
 	stat.Start()
 	compiler.Start()
+	for _, host := range config.Config.Hosts {
+		if pagesdir, _ := host.Config.GetString("pagesdir"); pagesdir != "" {
+			compiler.CleanVersions(host.Name, pagesdir)
+		}
+	}
+	for i := range config.Config.Hosts {
+		host := &config.Config.Hosts[i]
+		err = plugins.LoadApplications(host, logger.GetHostLogger(host.Name, "errors"))
+		if err != nil {
+			log.Println("Error loading the applications of the host: ", host.Name, err)
+			return err
+		}
+	}
+	logger.LinkHooks(lookupHook)
 	LinkEngines(config.Config.Engines)
+	return nil
+}
+
+// lookupHook returns the function exported by an application of the host, for the call: loggers.
+// The function is a func(*stat.RequestStat), a func(*assets.Context) or a func(string).
+func lookupHook(hostname string, application string, function string) (interface{}, error) {
+	for _, host := range config.Config.Hosts {
+		if host.Name != hostname {
+			continue
+		}
+		lib := host.Plugins[application]
+		var stathook func(*stat.RequestStat)
+		if err := plugins.Lookup(lib, function, &stathook); err == nil {
+			return stathook, nil
+		}
+		var hook func(*assets.Context)
+		if err := plugins.Lookup(lib, function, &hook); err == nil {
+			return hook, nil
+		}
+		var linehook func(string)
+		err := plugins.Lookup(lib, function, &linehook)
+		return linehook, err
+	}
+	return nil, errors.New("Error: the host " + hostname + " does not exist")
+}
+
+// Warmup loads the config file and warms up all the hosts (whatever is their warmup config), without opening any listener.
+// It is the command line mode to verify the pages and compile the libraries, for instance into a CI.
+// It returns an error if any page could not be loaded or compiled. The details are into the errors logs of each host.
+func Warmup(file string) error {
+
+	err := start(file)
+	if err != nil {
+		return err
+	}
+
+	numerrors := 0
+	for i := range config.Config.Hosts {
+		numerrors += len(WarmupHost(&config.Config.Hosts[i], false))
+	}
+	if numerrors > 0 {
+		return fmt.Errorf("Warm up failed with %d errors, please check the errors logs", numerrors)
+	}
+	return nil
+}
+
+func Run(file string) error {
+
+	err := start(file)
+	if err != nil {
+		return err
+	}
+
+	StartWatchers()
+
+	// Warm up the hosts before opening the listeners
+	xlogger := logger.GetCoreLogger("sys")
+	for i := range config.Config.Hosts {
+		host := &config.Config.Hosts[i]
+		if !host.Warmup.Enabled {
+			continue
+		}
+		errs := WarmupHost(host, host.Warmup.FailFast)
+		if len(errs) > 0 && host.Warmup.FailFast {
+			xlogger.Println("Warm up of host H["+host.Name+"] failed, the server is not started:", errs[0])
+			return errs[0]
+		}
+	}
 
 	http.HandleFunc("/", StatLoggerWrapper(mainHandler))
 
 	finish := make(chan bool)
 
 	// build the different servers
-	xlogger := logger.GetCoreLogger("sys")
 	for _, l := range config.Config.Listeners {
 		xlogger.Println("Scanning Listener: L[" + l.Name + "]")
 		go func(listener config.Listener) {
@@ -266,14 +384,27 @@ func Run(file string) error {
 				WriteTimeout:      time.Duration(listener.WriteTimeOut) * time.Second,
 				MaxHeaderBytes:    listener.HeaderSize,
 			}
+			var handler http.Handler = http.DefaultServeMux
+			if listener.Metrics.Enabled {
+				handler = metricsHandler(listener.Metrics, handler)
+			}
+			if listener.Admin.Enabled {
+				handler = adminHandler(listener.Admin, handler)
+			}
+			server.Handler = handler
 
 			// If the server is protocol HTTPS, we have to scan all the certificates for this listener
 			if listener.Protocol == "https" {
 				numcertificates := 0
+				// the client certificates are requested if the admin API of the listener or of one of its hosts uses them
+				clientcerts := listener.Admin.Enabled && listener.Admin.ClientCA != ""
 				// We search for all the hosts on this listener
 				for _, host := range config.Config.Hosts {
 					if utils.SearchInArray(listener.Name, host.Listeners) {
 						numcertificates++
+						if host.Admin.Enabled && host.Admin.ClientCA != "" {
+							clientcerts = true
+						}
 					}
 				}
 
@@ -321,6 +452,10 @@ func Run(file string) error {
 					}
 				}
 				tlsConfig.BuildNameToCertificate()
+				if clientcerts {
+					// the certificate is verified by the admin API against its own client CA
+					tlsConfig.ClientAuth = tls.RequestClientCert
+				}
 				server.TLSConfig = tlsConfig
 
 				xserver, err := tls.Listen("tcp", listener.IP+":"+listener.Port, tlsConfig)
@@ -334,7 +469,7 @@ func Run(file string) error {
 			} else {
 				// *******************************************
 				// VERIFICAR EL LISTEN AND SERVE POR DEFECTO SIN TLS; ESTA MAL IMPLEMENTADO: HAY QUE USAR EL HANDLER Y TIMEOUTS Y ETC
-				llogger.Fatal(http.ListenAndServe(listener.IP+":"+listener.Port, nil))
+				llogger.Fatal(http.ListenAndServe(listener.IP+":"+listener.Port, server.Handler))
 			}
 		}(l)
 
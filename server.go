@@ -1,13 +1,18 @@
 package xamboo
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"plugin"
 	"regexp"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tdewolff/minify"
 	"github.com/tdewolff/minify/css"
@@ -31,6 +36,8 @@ import (
 	"github.com/webability-go/xamboo/engines/template"
 	"github.com/webability-go/xamboo/engines/wajafapp"
 	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/plugins"
+	"github.com/webability-go/xamboo/stat"
 	"github.com/webability-go/xamboo/utils"
 )
 
@@ -51,25 +58,19 @@ func LinkEngines(engines []config.Engine) {
 			continue
 		}
 
-		// TODO(phil) Recompile the engine if not exists (*Plugin)
-
-		lib, err := plugin.Open(engine.Library)
+		// compiled if the code is given and the library does not exist or is older than the code
+		lib, err := plugins.Get(xloggererror, nil, engine.Code, engine.Library)
 		if err != nil {
 			xloggererror.Println("Error loading engine library:", engine.Library, err)
 			continue
 		}
 
-		enginelink, err := lib.Lookup("Engine")
+		var interf assets.Engine
+		err = plugins.Lookup(lib.Lib, "Engine", &interf)
 		if err != nil {
 			xloggererror.Println("Error linking engine main funcion Engine:", err)
 			continue
 		}
-
-		interf, ok := enginelink.(assets.Engine)
-		if !ok {
-			xloggererror.Println("Error linking engine main funcion Engine, is not of type assets.Engine.")
-			continue
-		}
 		Engines[engine.Name] = interf
 	}
 }
@@ -87,20 +88,53 @@ type Server struct {
 	MainContext   *assets.Context
 	Recursivity   map[string]int
 	GZipCandidate bool
+	RequestID     string
+
+	// context of the page actually running, the inner pages derive their context from it
+	ctx context.Context
+	// resolution chain of the pages, for the debug error renderer
+	resolution []string
+	// errors logger of the host with the id of the request
+	elogger *log.Logger
+	// leveled logger of the host with the id of the request
+	llogger *assets.LevelLogger
+	// the page actually running launched the error page or block
+	failed bool
+	// held by the goroutine that uses the server, the engines of the pages with a timeout run on their own goroutine
+	running *sync.Mutex
+	// context of the page whose engine runs on this copy of the server, nil for the server of the request
+	forked context.Context
 }
 
+// StatusClientClosedRequest is the code of a request canceled by the client before the page is calculated
+const StatusClientClosedRequest = 499
+
 func (s *Server) Start(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			hlogger := logger.GetHostLogger(s.Host.Name, "errors")
+			hlogger := s.errorsLogger()
 			hlogger.Println("Recovered in Server.Start", r, string(debug.Stack()))
-			w.(*CoreWriter).RequestStat.Code = http.StatusInternalServerError
+			cw := w.(*CoreWriter)
+			cw.RequestStat.UpdateCode(http.StatusInternalServerError)
+			// nothing has been sent yet: the client receives a real 500
+			if cw.status == 0 {
+				w.Header().Del("Content-Encoding")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				cw.GZip = false
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+			}
 		}
 	}()
 
 	s.writer = w
 	s.reader = r
+	if s.running == nil {
+		s.running = &sync.Mutex{}
+	}
+	s.running.Lock()
+	defer s.running.Unlock()
 
 	page := s.Page
 	// We clean the page,
@@ -157,7 +191,7 @@ func (s *Server) Start(w http.ResponseWriter, r *http.Request) {
 		}
 		newcode, err := m.String(contenttype, scode)
 		if err != nil {
-			elogger := logger.GetHostLogger(s.Host.Name, "errors")
+			elogger := s.errorsLogger()
 			elogger.Println(err)
 		} else {
 			scode = newcode
@@ -175,7 +209,7 @@ func (s *Server) Start(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.Code != http.StatusOK {
-		s.writer.(*CoreWriter).RequestStat.Code = s.Code
+		s.writer.(*CoreWriter).RequestStat.UpdateCode(s.Code)
 		s.writer.WriteHeader(s.Code)
 	}
 	s.writer.Write([]byte(scode))
@@ -183,12 +217,31 @@ func (s *Server) Start(w http.ResponseWriter, r *http.Request) {
 
 // The main xamboo runner
 // innerpage is false for the default page call, true when it's a subcall (inner call, with context)
-func (s *Server) Run(page string, innerpage bool, params interface{}, version string, language string, method string) interface{} {
+func (s *Server) Run(page string, innerpage bool, params interface{}, version string, language string, method string) (result interface{}) {
+
+	// nobody waits for the page of an abandoned engine
+	if s.abandoned() {
+		return s.forked.Err()
+	}
 
 	// page is the original page to scan
 	// P is the scanned page
 	P := page
 
+	// the time of the inner blocks is attributed to the block used, once it is known, with the size of the block and if it failed
+	var block stat.PageKey
+	if innerpage {
+		start := time.Now()
+		defer func(failed bool) {
+			if block.Page != "" && stat.SystemStat != nil {
+				sdata, _ := result.(string)
+				stat.SystemStat.Blocks.Record(block, time.Since(start), s.failed, len(sdata))
+			}
+			s.failed = failed
+		}(s.failed)
+		s.failed = false
+	}
+
 	// ==========================================================
 	// Chapter 1: Search the correct .page
 	// ==========================================================
@@ -202,8 +255,14 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 	for {
 		pagedata = pageserver.GetData(P)
 		if pagedata != nil && s.isAvailable(innerpage, pagedata) {
+			s.trace(page, "%s.page found", P)
 			break
 		}
+		if pagedata == nil {
+			s.trace(page, "%s.page not found", P)
+		} else {
+			s.trace(page, "%s.page not available", P)
+		}
 		// page not valid, we invalid it
 		pagedata = nil
 
@@ -221,15 +280,16 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 		// last chance: main page accept parameters too ?
 		P, _ = s.Host.Config.GetString("mainpage")
 		pagedata = pageserver.GetData(P)
+		s.trace(page, "main page %s.page tried with the full path as parameters", P)
 		if pagedata == nil || !s.isAvailable(innerpage, pagedata) {
-			return s.launchError(page, http.StatusNotFound, innerpage, "Error 404: no page found .page for "+page)
+			return s.launchError(page, http.StatusNotFound, innerpage, errors.New("Error 404: no page found .page for "+page))
 		}
 		fullpath = true
 	}
 	var xParams []string
 	if P != page {
 		if app, _ := pagedata.GetBool("acceptpathparameters"); !app {
-			return s.launchError(page, http.StatusNotFound, innerpage, "Error 404: no page found with parameters")
+			return s.launchError(page, http.StatusNotFound, innerpage, errors.New("Error 404: no page found with parameters"))
 		}
 		if fullpath {
 			xParams = strings.Split(page, "/")
@@ -245,13 +305,27 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 		LocalPage:           page,
 		LocalPageUsed:       P,
 		LocalURLparams:      xParams,
-		LoggerError:         logger.GetHostLogger(s.Host.Name, "errors"),
+		RequestID:           s.RequestID,
+		LoggerError:         s.errorsLogger(),
 		Sysparams:           s.Host.Config,
 		LocalPageparams:     pagedata,
 		LocalInstanceparams: nil,
 		LocalEntryparams:    params,
 		Plugins:             s.Host.Plugins,
 	}
+	ctx.Logger = s.pageLogger(P, pagedata)
+	var cancel context.CancelFunc
+	ctx.Ctx, cancel = s.pageContext(pagedata)
+	defer cancel()
+	defer func(parent context.Context) {
+		s.ctx = parent
+	}(s.ctx)
+	s.ctx = ctx.Ctx
+	// the timeout of the page (or of the calling page) is reached: the block is not calculated
+	if err := ctx.Ctx.Err(); err != nil {
+		return s.launchTimeout(page, innerpage, err)
+	}
+
 	if innerpage {
 		ctx.IsMainPage = false
 		ctx.Language = s.MainContext.Language
@@ -281,7 +355,7 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 		ctx.Sessionparams = xconfig.New()
 		s.MainContext = ctx
 	}
-	s.writer.(*CoreWriter).RequestStat.Context = ctx
+	s.writer.(*CoreWriter).RequestStat.UpdateContext(ctx)
 
 	// 1. Build-in engines
 	var xdata string
@@ -295,15 +369,23 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 	// ===========================================================
 	engine, ok := Engines[tp]
 	if !ok {
-		return s.launchError(page, http.StatusNotFound, !ctx.IsMainPage, "Error: Server "+tp+" does not exist")
+		return s.launchError(page, http.StatusNotFound, !ctx.IsMainPage, errors.New("Error: Server "+tp+" does not exist"))
 	}
 
 	if !engine.NeedInstance() {
 		// This engine does not need more than the .page itself.
-		data := engine.Run(ctx, s)
+		data, perr := s.runEngine(ctx, page, func(e *Server, ctx *assets.Context) interface{} {
+			return engine.Run(ctx, e)
+		})
+		if perr != nil {
+			return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, perr)
+		}
+		if err := ctx.Ctx.Err(); err != nil {
+			return s.launchTimeout(page, !ctx.IsMainPage, err)
+		}
 		dataerror, okerr := data.(error)
 		if okerr {
-			return s.launchError(page, ctx.Code, !ctx.IsMainPage, dataerror.Error())
+			return s.launchError(page, ctx.Code, !ctx.IsMainPage, dataerror)
 		}
 		return data
 	}
@@ -346,17 +428,25 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 	for _, n := range identities {
 		instancedata = instanceserver.GetData(P, n)
 		if instancedata != nil {
+			s.trace(page, "%s%s.instance found", P, n.Stringify())
+			if !innerpage {
+				// the page really used is written into the pages log
+				s.writer.(*CoreWriter).RequestStat.UpdatePage(P, tp, n.Stringify())
+			} else {
+				block = stat.PageKey{Host: s.Host.Name, Page: P, Engine: tp}
+			}
 			break
 		}
+		s.trace(page, "%s%s.instance not found", P, n.Stringify())
 	}
 
 	if instancedata == nil {
-		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, "Error: the page/block has no instance")
+		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, errors.New("Error: the page/block has no instance"))
 	}
 
 	// verify the possible recursion
 	if r, c := s.verifyRecursion(P, ctx.LocalPageparams); r {
-		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, "Error: the page/block is recursive: "+P+" after "+strconv.Itoa(c)+" times")
+		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, errors.New("Error: the page/block is recursive: "+P+" after "+strconv.Itoa(c)+" times"))
 	}
 
 	//  s.pushContext(innerpage, page, P, instancedata, params, version, language)
@@ -371,12 +461,14 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 	for _, n := range identities {
 		engineinstance = engine.GetInstance(s.Host.Name, s.PagesDir, P, n)
 		if engineinstance != nil {
+			s.trace(page, "%s engine instance found for the identity [%s]", tp, n.Stringify())
 			break
 		}
+		s.trace(page, "%s engine instance not found for the identity [%s]", tp, n.Stringify())
 	}
 
 	if engineinstance == nil {
-		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, "Error: the engine could not find an instance to Run. Please verify the available instances.")
+		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, errors.New("Error: the engine could not find an instance to Run. Please verify the available instances."))
 	}
 
 	var templatedata *xcore.XTemplate = nil
@@ -413,11 +505,19 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 		}
 	}
 
-	data := engineinstance.Run(ctx, templatedata, languagedata, s)
+	data, perr := s.runEngine(ctx, page, func(e *Server, ctx *assets.Context) interface{} {
+		return engineinstance.Run(ctx, templatedata, languagedata, e)
+	})
+	if perr != nil {
+		return s.launchError(page, http.StatusInternalServerError, !ctx.IsMainPage, perr)
+	}
+	if err := ctx.Ctx.Err(); err != nil {
+		return s.launchTimeout(page, !ctx.IsMainPage, err)
+	}
 	// if data is an error, launch the error page (the error has already been generated and handled)
 	dataerror, okerr := data.(error)
 	if okerr {
-		return s.launchError(page, ctx.Code, !ctx.IsMainPage, dataerror.Error())
+		return s.launchError(page, ctx.Code, !ctx.IsMainPage, dataerror)
 	}
 	_, okstr := data.(string)
 	if innerpage && !okstr { // If Data is not string so it may be any type of data for the caller. We will not incapsulate it into a template, even if asked
@@ -435,7 +535,10 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 
 	// check templates and get templates
 	if x, _ := ctx.LocalPageparams.GetString("template"); x != "" {
-		fathertemplate := s.Run(x, true, params, version, language, method).(string)
+		fathertemplate := fmt.Sprint(s.Run(x, true, params, version, language, method))
+		if err := ctx.Ctx.Err(); err != nil {
+			return s.launchTimeout(page, !ctx.IsMainPage, err)
+		}
 		//    if (is_array($text))
 		//    {
 		//      foreach($text as $k => $block)
@@ -464,21 +567,43 @@ func (s *Server) Run(page string, innerpage bool, params interface{}, version st
 }
 
 func wrapper(s interface{}, page string, params interface{}, version string, language string, method string) interface{} {
-	return s.(*Server).Run(page, true, params, version, language, method)
+	server := s.(*Server)
+	if server.forked != nil {
+		// the engine runs on its own goroutine
+		server.running.Lock()
+		defer server.running.Unlock()
+	}
+	return server.Run(page, true, params, version, language, method)
 }
 
 func wrapperstring(s interface{}, page string, params interface{}, version string, language string, method string) string {
-	data := s.(*Server).Run(page, true, params, version, language, method)
+	data := wrapper(s, page, params, version, language, method)
 	if sdata, ok := data.(string); ok {
 		return sdata
 	}
 	return fmt.Sprint(data)
 }
 
-func (s *Server) launchError(page string, code int, innerpage bool, message string) interface{} {
+func (s *Server) launchError(page string, code int, innerpage bool, err error) interface{} {
 	// error page or error block?
 	// WE LOG THIS ERROR: this is some programmation error normally
-	elogger := logger.GetHostLogger(s.Host.Name, "errors")
+	s.failed = true
+	elogger := s.errorsLogger()
+	message := err.Error()
+
+	if !innerpage && s.wantsProblem() {
+		elogger.Println(code, page, message)
+		s.Code = code
+		return s.renderProblem(page, code, err)
+	}
+
+	if s.Host.Debug {
+		elogger.Println(code, page, message)
+		if !innerpage {
+			s.Code = code
+		}
+		return s.renderDebugError(page, code, innerpage, err)
+	}
 
 	errpage := ""
 	if innerpage {
@@ -499,14 +624,207 @@ func (s *Server) launchError(page string, code int, innerpage bool, message stri
 		}
 	}
 	data := map[string]interface{}{
-		"page":    page,
-		"code":    code,
-		"message": message,
+		"page":      page,
+		"code":      code,
+		"message":   message,
+		"requestid": s.RequestID,
 	}
 	elogger.Println(code, page, message)
 	return s.Run(errpage, innerpage, data, "", "", "")
 }
 
+// errorsLogger returns the errors logger of the host, with the id of the request on each line
+func (s *Server) errorsLogger() *log.Logger {
+	if s.elogger == nil {
+		s.elogger = logger.WithRequestID(logger.GetHostLogger(s.Host.Name, "errors"), s.RequestID)
+	}
+	return s.elogger
+}
+
+// pageLogger returns the leveled logger of the page: the logger of the host, with the loglevel of the .page if any, tagged with the page and the request id
+func (s *Server) pageLogger(P string, pagedata *xconfig.XConfig) *assets.LevelLogger {
+	if s.llogger == nil {
+		s.llogger = logger.GetHostLevelLogger(s.Host.Name)
+		s.llogger.Output = logger.WithRequestID(s.llogger.Output, s.RequestID)
+		s.llogger.ErrorOutput = s.errorsLogger()
+	}
+	l := s.llogger.With("page=" + P)
+	if name, _ := pagedata.GetString("loglevel"); name != "" {
+		level, err := assets.ParseLogLevel(name)
+		if err != nil {
+			s.errorsLogger().Println("Error in the loglevel of the page", P, err)
+		} else {
+			l.Level = level
+		}
+	}
+	return l
+}
+
+// PanicError is the error of a page that panicked, with the stack of the panic
+type PanicError struct {
+	Page  string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Error: panic in the page %s: %v", e.Page, e.Value)
+}
+
+// safeRun runs the engine and recovers a panic of the page, so only this page or block is replaced by the error page or block.
+// The stack of the panic is logged into the errors log of the host.
+func (s *Server) safeRun(page string, run func() interface{}) (data interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr := &PanicError{Page: page, Value: r, Stack: debug.Stack()}
+			s.errorsLogger().Println(perr.Error(), string(perr.Stack))
+			data = nil
+			err = perr
+		}
+	}()
+	return run(), nil
+}
+
+// runEngine runs the engine of the page with safeRun.
+// When the context of the page has a deadline (and always on a copy of the server), the engine runs on its own goroutine with a copy of the server
+// and the page does not wait for it once the context is done: the engine is abandoned and its inner blocks are not calculated anymore.
+// The server and its copies are used by one goroutine at a time, the one that holds the running mutex.
+// The engine of a copy writes into its own forkWriter: its response is sent to the writer of the page only if it returns in time.
+func (s *Server) runEngine(ctx *assets.Context, page string, run func(e *Server, ctx *assets.Context) interface{}) (interface{}, error) {
+	if _, ok := ctx.Ctx.Deadline(); !ok && s.forked == nil {
+		return s.safeRun(page, func() interface{} {
+			return run(s, ctx)
+		})
+	}
+
+	type result struct {
+		data interface{}
+		err  error
+	}
+	fw := &forkWriter{ctx: ctx.Ctx, header: s.writer.Header().Clone()}
+	cw := &CoreWriter{ResponseWriter: fw, RequestStat: s.writer.(*CoreWriter).RequestStat}
+	fork := *s
+	fork.forked = ctx.Ctx
+	fork.writer = cw
+	// the appends of the fork never write into the resolution of the server
+	fork.resolution = s.resolution[:len(s.resolution):len(s.resolution)]
+	fctx := *ctx
+	fctx.Writer = cw
+	if s.MainContext == ctx {
+		fork.MainContext = &fctx
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := fork.safeRun(page, func() interface{} {
+			return run(&fork, &fctx)
+		})
+		// the pooled writers are released only when the engine really ends
+		if cw.GZip {
+			cw.GZipWriter.Close()
+			zippers.Put(cw.GZipWriter)
+		}
+		done <- result{data: data, err: err}
+	}()
+
+	s.running.Unlock()
+	select {
+	case r := <-done:
+		s.running.Lock()
+		s.resolution = fork.resolution
+		fctx.Writer = ctx.Writer
+		*ctx = fctx
+		fw.send(s.writer)
+		return r.data, r.err
+	case <-ctx.Ctx.Done():
+		// the caller launches the timeout
+		s.running.Lock()
+		return nil, nil
+	}
+}
+
+// forkWriter keeps the headers, the code and the body written by an engine running on its own goroutine, until the engine returns.
+// The writes are dropped once the context of the page is done: the page does not wait for the engine anymore.
+type forkWriter struct {
+	ctx    context.Context
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (fw *forkWriter) Header() http.Header {
+	return fw.header
+}
+
+func (fw *forkWriter) WriteHeader(status int) {
+	if fw.ctx.Err() == nil && fw.status == 0 {
+		fw.status = status
+	}
+}
+
+func (fw *forkWriter) Write(b []byte) (int, error) {
+	if err := fw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return fw.body.Write(b)
+}
+
+// send writes the response kept by the fork into the writer of the page
+func (fw *forkWriter) send(w http.ResponseWriter) {
+	header := w.Header()
+	for name := range header {
+		if _, ok := fw.header[name]; !ok {
+			delete(header, name)
+		}
+	}
+	for name, values := range fw.header {
+		header[name] = values
+	}
+	if fw.status != 0 {
+		w.WriteHeader(fw.status)
+	}
+	if fw.body.Len() > 0 {
+		w.Write(fw.body.Bytes())
+	}
+}
+
+// abandoned is true if the server is a copy running an engine that the calling page does not wait for anymore
+func (s *Server) abandoned() bool {
+	return s.forked != nil && s.forked.Err() != nil
+}
+
+// pageContext derives the context of the page from the context of the calling page (the request for the main page),
+// with the timeout of the .page if any (timeout parameter in seconds).
+func (s *Server) pageContext(pagedata *xconfig.XConfig) (context.Context, context.CancelFunc) {
+	parent := s.ctx
+	if parent == nil {
+		parent = s.reader.Context()
+	}
+	timeout, ok := pagedata.GetFloat("timeout")
+	if !ok {
+		itimeout, _ := pagedata.GetInt("timeout")
+		timeout = float64(itimeout)
+	}
+	if timeout > 0 {
+		return context.WithTimeout(parent, time.Duration(timeout*float64(time.Second)))
+	}
+	return context.WithCancel(parent)
+}
+
+// launchTimeout launches the error page or block when the context of the page is done:
+// 504 if the timeout is reached, 499 if the client is gone.
+func (s *Server) launchTimeout(page string, innerpage bool, err error) interface{} {
+	if s.abandoned() {
+		return err
+	}
+	code := http.StatusGatewayTimeout
+	if errors.Is(err, context.Canceled) {
+		code = StatusClientClosedRequest
+	}
+	// the error page does not inherit the expired context
+	s.ctx = nil
+	return s.launchError(page, code, innerpage, fmt.Errorf("Error %d: the page %s was aborted: %w", code, page, err))
+}
+
 func (s *Server) launchRedirect(url string) {
 	// Call the redirect mecanism
 	http.Redirect(s.writer, s.reader, url, http.StatusPermanentRedirect)
@@ -568,8 +886,8 @@ func (s *Server) analyzeUserAgent() string {
 	return devices[ua.DeviceType]
 }
 
-// GetFullConfig for admin functions. See how to protect this
-// TODO(phi) protect GetFullConfig
+// GetFullConfig for admin functions, with the secrets: it is only given to the code of the server (engines and applications).
+// The admin API gives the config with RedactedConfig.
 func (s *Server) GetFullConfig() *config.ConfigDef {
 	return config.Config
 }
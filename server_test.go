@@ -0,0 +1,354 @@
+package xamboo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xconfig"
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/stat"
+)
+
+// testEngine is an engine whose instances run the function of the page
+type testEngine map[string]testInstance
+
+func (te testEngine) NeedInstance() bool {
+	return true
+}
+
+func (te testEngine) GetInstance(Hostname string, PagesDir string, P string, i assets.Identity) assets.EngineInstance {
+	if run, ok := te[P]; ok {
+		return run
+	}
+	return nil
+}
+
+func (te testEngine) Run(ctx *assets.Context, e interface{}) interface{} {
+	return nil
+}
+
+type testInstance func(ctx *assets.Context, e interface{}) interface{}
+
+func (ti testInstance) NeedLanguage() bool {
+	return false
+}
+
+func (ti testInstance) NeedTemplate() bool {
+	return false
+}
+
+func (ti testInstance) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, e interface{}) interface{} {
+	return ti(ctx, e)
+}
+
+// testHost creates the pages of the engine into a temporary directory, each .page with the given parameters.
+// The returned function removes the pages and restores the engines.
+func testHost(t *testing.T, engine testEngine, pages map[string]string) (*assets.Host, string, func()) {
+	dir, err := ioutil.TempDir("", "xamboo-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for P, params := range pages {
+		if err := os.MkdirAll(filepath.Join(dir, P), 0755); err != nil {
+			t.Fatal(err)
+		}
+		page := "type=test\nstatus=published\n" + params + "\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, P, P+".page"), []byte(page), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, P, P+".instance"), []byte("test=1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved, savedwrapper := Engines["test"], assets.EngineWrapperString
+	Engines["test"] = engine
+	assets.EngineWrapperString = wrapperstring
+
+	host := &assets.Host{Name: "test", Config: xconfig.New()}
+	host.Config.Set("mainpage", "home")
+	return host, dir + "/", func() {
+		os.RemoveAll(dir)
+		Engines["test"] = saved
+		assets.EngineWrapperString = savedwrapper
+	}
+}
+
+// testRequest serves the request on the host like the main handler
+func testRequest(host *assets.Host, pagesdir string, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	cw := &CoreWriter{ResponseWriter: w, RequestStat: &stat.RequestStat{}}
+	server := &Server{
+		Method:      r.Method,
+		Page:        r.URL.Path,
+		Host:        host,
+		PagesDir:    pagesdir,
+		Code:        http.StatusOK,
+		Recursivity: map[string]int{},
+		RequestID:   "test",
+	}
+	server.Start(cw, r)
+	return w
+}
+
+func TestPageTimeout(t *testing.T) {
+	release := make(chan bool)
+	abandoned := make(chan string, 2)
+	slow := func(ctx *assets.Context, e interface{}) interface{} {
+		<-release
+		// the page does not wait anymore, the inner blocks are not calculated
+		abandoned <- assets.EngineWrapperString(e, "fast", nil, "", "", "")
+		return "slow"
+	}
+	host, dir, cleanup := testHost(t, testEngine{
+		"slow":      slow,
+		"slowblock": slow,
+		"fast": func(ctx *assets.Context, e interface{}) interface{} {
+			return "fast"
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return "main " + assets.EngineWrapperString(e, "fast", nil, "", "", "") + " " + assets.EngineWrapperString(e, "slowblock", nil, "", "", "")
+		},
+	}, map[string]string{
+		"slow":      "timeout=0.05",
+		"slowblock": "timeout=0.05",
+		"fast":      "",
+		"main":      "timeout=5",
+	})
+	defer cleanup()
+
+	start := time.Now()
+	w := testRequest(host, dir, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("slow page: got the code %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+
+	w = testRequest(host, dir, httptest.NewRequest("GET", "/main", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("page with a slow block: got the code %d, want %d", w.Code, http.StatusOK)
+	}
+	// without errorblock, the slow block is replaced by the message of the config
+	if body := w.Body.String(); !strings.HasPrefix(body, "main fast ") || !strings.Contains(body, "errorblock") {
+		t.Errorf("page with a slow block: got %q", body)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("the pages waited %v for the slow blocks", d)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case data := <-abandoned:
+			if data != "context deadline exceeded" {
+				t.Errorf("block of an abandoned page: got %q", data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the abandoned page never ended")
+		}
+	}
+}
+
+func TestPageTimeoutWriter(t *testing.T) {
+	release := make(chan bool)
+	late := make(chan error, 1)
+	host, dir, cleanup := testHost(t, testEngine{
+		"late": func(ctx *assets.Context, e interface{}) interface{} {
+			ctx.Writer.Header().Set("X-Early", "1")
+			ctx.Writer.Write([]byte("EARLY"))
+			<-release
+			ctx.Writer.Header().Set("X-Late", "1")
+			_, err := ctx.Writer.Write([]byte("LATE"))
+			late <- err
+			return "late"
+		},
+		"intime": func(ctx *assets.Context, e interface{}) interface{} {
+			ctx.Writer.Header().Set("X-Intime", "1")
+			ctx.Writer.WriteHeader(http.StatusAccepted)
+			ctx.Writer.Write([]byte("written "))
+			return "returned"
+		},
+	}, map[string]string{"late": "timeout=0.05", "intime": "timeout=5"})
+	defer cleanup()
+
+	// the response of an engine that returns in time is sent
+	w := testRequest(host, dir, httptest.NewRequest("GET", "/intime", nil))
+	if w.Code != http.StatusAccepted || w.Header().Get("X-Intime") != "1" || w.Body.String() != "written returned" {
+		t.Errorf("page in time: got the code %d, the headers %v and the body %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	// nothing written by an abandoned engine reaches the response
+	w = testRequest(host, dir, httptest.NewRequest("GET", "/late", nil))
+	close(release)
+	select {
+	case err := <-late:
+		if err == nil {
+			t.Error("write of an abandoned engine accepted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the abandoned page never ended")
+	}
+	if w.Code != http.StatusGatewayTimeout || strings.Contains(w.Body.String(), "EARLY") || strings.Contains(w.Body.String(), "LATE") {
+		t.Errorf("late page: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Early") != "" || w.Header().Get("X-Late") != "" {
+		t.Errorf("late page: got the headers %v", w.Header())
+	}
+}
+
+func TestBlockStats(t *testing.T) {
+	saved := stat.SystemStat
+	stat.SystemStat = &stat.Stat{Blocks: stat.NewPageStats()}
+	defer func() { stat.SystemStat = saved }()
+
+	host, dir, cleanup := testHost(t, testEngine{
+		"ok": func(ctx *assets.Context, e interface{}) interface{} {
+			return "12345"
+		},
+		"failed": func(ctx *assets.Context, e interface{}) interface{} {
+			ctx.Code = http.StatusInternalServerError
+			return errors.New("the block failed")
+		},
+		// a block with a failed inner block does not fail itself
+		"outer": func(ctx *assets.Context, e interface{}) interface{} {
+			return "[" + assets.EngineWrapperString(e, "failed", nil, "", "", "") + "]"
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return assets.EngineWrapperString(e, "ok", nil, "", "", "") + assets.EngineWrapperString(e, "outer", nil, "", "", "")
+		},
+	}, map[string]string{"ok": "", "failed": "", "outer": "", "main": ""})
+	defer cleanup()
+
+	w := testRequest(host, dir, httptest.NewRequest("GET", "/main", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got the code %d, want %d", w.Code, http.StatusOK)
+	}
+
+	blocks := map[string]stat.PageStat{}
+	for _, b := range stat.SystemStat.Blocks.Get("test") {
+		blocks[b.Page] = b
+	}
+	// without errorblock, the failed block is replaced by the message of the config
+	failed := uint64(len(strings.Trim(w.Body.String()[5:], "[]")))
+	for page, want := range map[string]struct {
+		errors uint64
+		bytes  uint64
+	}{
+		"ok":     {0, 5},
+		"failed": {1, failed},
+		"outer":  {0, failed + 2},
+	} {
+		b, ok := blocks[page]
+		if !ok {
+			t.Errorf("block %s not recorded", page)
+			continue
+		}
+		if b.Count != 1 || b.Errors != want.errors || b.Bytes != want.bytes {
+			t.Errorf("block %s: got %d calls, %d errors and %d bytes, want 1, %d and %d", page, b.Count, b.Errors, b.Bytes, want.errors, want.bytes)
+		}
+	}
+	if _, ok := blocks["main"]; ok {
+		t.Error("the main page is recorded as a block")
+	}
+}
+
+func TestErrorRequestID(t *testing.T) {
+	errorpage := func(ctx *assets.Context, e interface{}) interface{} {
+		params, _ := ctx.LocalEntryparams.(map[string]interface{})
+		return fmt.Sprint(ctx.LocalPage, " ", params["code"], " ", params["requestid"])
+	}
+	host, dir, cleanup := testHost(t, testEngine{
+		"panic": func(ctx *assets.Context, e interface{}) interface{} {
+			panic("the page panicked")
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return "main " + assets.EngineWrapperString(e, "panic", nil, "", "", "")
+		},
+		"error": errorpage,
+		"block": errorpage,
+	}, map[string]string{"panic": "", "main": "", "error": "", "block": ""})
+	defer cleanup()
+	host.Config.Set("errorpage", "error")
+	host.Config.Set("errorblock", "block")
+
+	// the id of the request is given to the error page and to the error block, so the user can report it
+	w := testRequest(host, dir, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "error 500 test" {
+		t.Errorf("error page: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+	w = testRequest(host, dir, httptest.NewRequest("GET", "/main", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "main block 500 test" {
+		t.Errorf("error block: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+}
+
+// testApplication is an application of the host that runs start on each main page
+type testApplication struct {
+	assets.Application
+	start func(ctx *assets.Context)
+}
+
+func (a testApplication) StartContext(ctx *assets.Context) {
+	a.start(ctx)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	host, dir, cleanup := testHost(t, testEngine{
+		"panic": func(ctx *assets.Context, e interface{}) interface{} {
+			panic("the page panicked")
+		},
+		"main": func(ctx *assets.Context, e interface{}) interface{} {
+			return "main " + assets.EngineWrapperString(e, "panic", nil, "", "", "")
+		},
+	}, map[string]string{"panic": "", "main": ""})
+	defer cleanup()
+
+	// only the page that panics is replaced by the error page or block
+	w := testRequest(host, dir, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "errorpage") {
+		t.Errorf("panic of the main page: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+	w = testRequest(host, dir, httptest.NewRequest("GET", "/main", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "main ") || !strings.Contains(w.Body.String(), "errorblock") {
+		t.Errorf("panic of a block: got the code %d and the body %q", w.Code, w.Body.String())
+	}
+
+	// a panic out of the pages is recovered by Start: a 500 only if nothing has been sent yet
+	for _, test := range []struct {
+		name  string
+		write bool
+		code  int
+		body  string
+	}{
+		{"nothing sent", false, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)},
+		{"response started", true, http.StatusOK, "partial"},
+	} {
+		write := test.write
+		host.Applications = map[string]assets.Application{"test": testApplication{start: func(ctx *assets.Context) {
+			if write {
+				ctx.Writer.Write([]byte("partial"))
+			}
+			panic("the application panicked")
+		}}}
+		w := httptest.NewRecorder()
+		cw := &CoreWriter{ResponseWriter: w, RequestStat: &stat.RequestStat{}}
+		server := &Server{Page: "/main", Host: host, PagesDir: dir, Code: http.StatusOK, Recursivity: map[string]int{}}
+		server.Start(cw, httptest.NewRequest("GET", "/main", nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s: got the code %d and the body %q, want %d and %q", test.name, w.Code, w.Body.String(), test.code, test.body)
+		}
+		if cw.RequestStat.Code != http.StatusInternalServerError {
+			t.Errorf("%s: the stat of the request has the code %d", test.name, cw.RequestStat.Code)
+		}
+	}
+	host.Applications = nil
+}
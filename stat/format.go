@@ -0,0 +1,127 @@
+package stat
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// The log formats of the pages log of a host ("format" parameter of the log section of the host)
+const (
+	FormatCommon   = "common"
+	FormatCombined = "combined"
+	FormatJSON     = "json"
+)
+
+// DefaultFields are the fields written in a json line when no "fields" are set into the log section of the host
+var DefaultFields = []string{"time", "requestid", "host", "ip", "method", "uri", "protocol", "code", "length", "duration", "page", "engine", "identity", "gzip", "zlength"}
+
+var logFormats map[string]assets.Log
+
+// Format returns the log line of the request in the given format: common, combined or json.
+// fields are the fields of the json line, in order. Any other format returns the default xamboo line.
+func (r *RequestStat) Format(format string, fields []string) string {
+	switch format {
+	case FormatCommon:
+		return r.common()
+	case FormatCombined:
+		return r.common() + " " + strconv.Quote(r.Referer) + " " + strconv.Quote(r.UserAgent)
+	case FormatJSON:
+		return r.jsonLine(fields)
+	}
+	return strings.Join([]string{r.IP, r.Method, r.Protocol, strconv.Itoa(r.Code), r.Request, strconv.Itoa(r.Length), r.Duration.String()}, " ")
+}
+
+// common builds the Apache common log line: ip - user [time] "method uri protocol" code length
+func (r *RequestStat) common() string {
+	user := r.User
+	if user == "" {
+		user = "-"
+	}
+	length := r.Length
+	if r.GZip {
+		length = r.ZLength
+	}
+	size := "-"
+	if length > 0 {
+		size = strconv.Itoa(length)
+	}
+	return r.IP + " - " + user + " [" + r.StartTime.Format("02/Jan/2006:15:04:05 -0700") + "] " +
+		strconv.Quote(r.Method+" "+r.URI+" "+r.Protocol) + " " + strconv.Itoa(r.Code) + " " + size
+}
+
+// jsonLine builds a json object with the given fields. Unknown fields are ignored.
+func (r *RequestStat) jsonLine(fields []string) string {
+	if len(fields) == 0 {
+		fields = DefaultFields
+	}
+	line := []byte{'{'}
+	for _, field := range fields {
+		value, ok := r.field(field)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		if len(line) > 1 {
+			line = append(line, ',')
+		}
+		line = append(line, strconv.Quote(field)...)
+		line = append(line, ':')
+		line = append(line, data...)
+	}
+	return string(append(line, '}'))
+}
+
+func (r *RequestStat) field(name string) (interface{}, bool) {
+	switch name {
+	case "time":
+		return r.StartTime.Format(time.RFC3339Nano), true
+	case "id":
+		return r.Id, true
+	case "requestid":
+		return r.RequestID, true
+	case "host":
+		return r.Hostname, true
+	case "request":
+		return r.Request, true
+	case "ip":
+		return r.IP, true
+	case "port":
+		return r.Port, true
+	case "user":
+		return r.User, true
+	case "method":
+		return r.Method, true
+	case "uri":
+		return r.URI, true
+	case "protocol":
+		return r.Protocol, true
+	case "code":
+		return r.Code, true
+	case "length":
+		return r.Length, true
+	case "zlength":
+		return r.ZLength, true
+	case "gzip":
+		return r.GZip, true
+	case "duration":
+		return r.Duration.Seconds(), true
+	case "referer":
+		return r.Referer, true
+	case "useragent":
+		return r.UserAgent, true
+	case "page":
+		return r.Page, true
+	case "engine":
+		return r.Engine, true
+	case "identity":
+		return r.Identity, true
+	}
+	return nil, false
+}
@@ -0,0 +1,56 @@
+package stat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testRequestStat() *RequestStat {
+	return &RequestStat{RequestData: RequestData{
+		RequestID: "abc-1",
+		StartTime: time.Date(2020, 8, 18, 10, 11, 12, 0, time.UTC),
+		Hostname:  "developers",
+		URI:       "/home?x=1",
+		Referer:   "http://example.com/",
+		UserAgent: "Mozilla/5.0",
+		Method:    "GET",
+		Protocol:  "HTTP/1.1",
+		Code:      200,
+		Length:    1000,
+		ZLength:   300,
+		GZip:      true,
+		Page:      "home",
+		Engine:    "simple",
+		Identity:  ".pc.es",
+		IP:        "127.0.0.1",
+	}}
+}
+
+func TestFormatCommon(t *testing.T) {
+	r := testRequestStat()
+	want := `127.0.0.1 - - [18/Aug/2020:10:11:12 +0000] "GET /home?x=1 HTTP/1.1" 200 300`
+	if got := r.Format(FormatCommon, nil); got != want {
+		t.Errorf("common: got %s, want %s", got, want)
+	}
+	want += ` "http://example.com/" "Mozilla/5.0"`
+	if got := r.Format(FormatCombined, nil); got != want {
+		t.Errorf("combined: got %s, want %s", got, want)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	r := testRequestStat()
+	line := r.Format(FormatJSON, []string{"requestid", "page", "gzip", "length", "zlength", "unknown"})
+	if line != `{"requestid":"abc-1","page":"home","gzip":true,"length":1000,"zlength":300}` {
+		t.Errorf("json: got %s", line)
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(r.Format(FormatJSON, nil)), &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(DefaultFields) || data["identity"] != ".pc.es" {
+		t.Errorf("json default fields: got %v", data)
+	}
+}
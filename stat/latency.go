@@ -0,0 +1,142 @@
+package stat
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the buckets of the latencies: from 100µs, each bucket 10% larger than the previous one, up to more than 1 minute.
+// The quantiles are given with less than 10% of error.
+var latencyBounds = func() []time.Duration {
+	bounds := []time.Duration{}
+	for b := float64(100 * time.Microsecond); b < float64(2*time.Minute); b *= 1.1 {
+		bounds = append(bounds, time.Duration(b))
+	}
+	return bounds
+}()
+
+// Latency counts the durations of a page into log-scale buckets, to get its quantiles
+type Latency struct {
+	Count  uint64
+	Errors uint64
+	Bytes  uint64
+	Sum    time.Duration
+	Max    time.Duration
+	counts []uint64 // by latencyBounds, the last one is for the larger durations
+}
+
+func (l *Latency) observe(d time.Duration, failed bool, bytes int) {
+	if l.counts == nil {
+		l.counts = make([]uint64, len(latencyBounds)+1)
+	}
+	i := sort.Search(len(latencyBounds), func(i int) bool { return latencyBounds[i] >= d })
+	l.counts[i]++
+	l.Count++
+	if failed {
+		l.Errors++
+	}
+	l.Bytes += uint64(bytes)
+	l.Sum += d
+	if d > l.Max {
+		l.Max = d
+	}
+}
+
+// Quantile returns the duration under which are the q (0 to 1) part of the durations
+func (l *Latency) Quantile(q float64) time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(l.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	n := uint64(0)
+	for i, c := range l.counts {
+		n += c
+		if n >= rank {
+			if i < len(latencyBounds) && latencyBounds[i] < l.Max {
+				return latencyBounds[i]
+			}
+			return l.Max
+		}
+	}
+	return l.Max
+}
+
+// PageKey identifies a page or a block of a host, with its engine
+type PageKey struct {
+	Host   string
+	Page   string
+	Engine string
+}
+
+// PageStat are the stats of a page or a block
+type PageStat struct {
+	PageKey
+	Count  uint64
+	Errors uint64 // code >= 500
+	Bytes  uint64
+	Mean   time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// PageStats are the latencies of the pages (or blocks) by host, page and engine
+type PageStats struct {
+	mutex sync.Mutex
+	pages map[PageKey]*Latency
+}
+
+func NewPageStats() *PageStats {
+	return &PageStats{pages: map[PageKey]*Latency{}}
+}
+
+// Record adds a duration of the page. failed is true if the page returned an error.
+func (p *PageStats) Record(key PageKey, d time.Duration, failed bool, bytes int) {
+	p.mutex.Lock()
+	l, ok := p.pages[key]
+	if !ok {
+		l = &Latency{}
+		p.pages[key] = l
+	}
+	l.observe(d, failed, bytes)
+	p.mutex.Unlock()
+}
+
+// Get returns the stats of the pages of the host (of all the hosts if host is empty), ordered by host, page and engine
+func (p *PageStats) Get(host string) []PageStat {
+	list := []PageStat{}
+	p.mutex.Lock()
+	for k, l := range p.pages {
+		if host != "" && k.Host != host {
+			continue
+		}
+		list = append(list, PageStat{
+			PageKey: k,
+			Count:   l.Count,
+			Errors:  l.Errors,
+			Bytes:   l.Bytes,
+			Mean:    l.Sum / time.Duration(l.Count),
+			P50:     l.Quantile(0.50),
+			P95:     l.Quantile(0.95),
+			P99:     l.Quantile(0.99),
+			Max:     l.Max,
+		})
+	}
+	p.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		return a.Engine < b.Engine
+	})
+	return list
+}
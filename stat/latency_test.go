@@ -0,0 +1,35 @@
+package stat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPageStats(t *testing.T) {
+	p := NewPageStats()
+	home := PageKey{Host: "developers", Page: "home", Engine: "simple"}
+	for i := 1; i <= 100; i++ {
+		p.Record(home, time.Duration(i)*time.Millisecond, i > 98, 10)
+	}
+	p.Record(PageKey{Host: "admin", Page: "home", Engine: "library"}, time.Second, false, 0)
+
+	all := p.Get("")
+	if len(all) != 2 || all[0].Host != "admin" {
+		t.Fatalf("all hosts: got %v", all)
+	}
+	list := p.Get("developers")
+	if len(list) != 1 {
+		t.Fatalf("developers: got %v", list)
+	}
+	s := list[0]
+	if s.Count != 100 || s.Errors != 2 || s.Bytes != 1000 || s.Max != 100*time.Millisecond {
+		t.Errorf("counters: got %+v", s)
+	}
+	for _, q := range []struct {
+		got, want time.Duration
+	}{{s.P50, 50 * time.Millisecond}, {s.P95, 95 * time.Millisecond}, {s.P99, 99 * time.Millisecond}} {
+		if q.got < q.want || q.got > q.want*11/10 {
+			t.Errorf("quantile: got %v, want %v (+10%%)", q.got, q.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package stat
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DurationBuckets are the upper bounds in seconds of the buckets of the histograms of the durations of the requests
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// RequestKey are the labels of the counters of requests
+type RequestKey struct {
+	Host   string
+	Code   int
+	Method string
+	Engine string
+}
+
+// Histogram counts the durations by bucket. Counts[i] is the number of durations <= DurationBuckets[i] (not cumulative), the last one is +Inf.
+type Histogram struct {
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func (h *Histogram) observe(seconds float64) {
+	i := sort.SearchFloat64s(DurationBuckets, seconds)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += seconds
+}
+
+// Metrics are the counters of the requests for the metrics endpoint
+type Metrics struct {
+	inflight int64 // first field, for the 64 bits alignment of the atomic operations
+
+	mutex     sync.Mutex
+	requests  map[RequestKey]uint64
+	durations map[string]*Histogram // by host
+	bytes     map[string]uint64     // bytes sent by host, after compression
+}
+
+// MetricsSnapshot is a copy of the metrics
+type MetricsSnapshot struct {
+	InFlight  int64
+	Requests  map[RequestKey]uint64
+	Durations map[string]Histogram
+	Bytes     map[string]uint64
+}
+
+var SystemMetrics = NewMetrics()
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  map[RequestKey]uint64{},
+		durations: map[string]*Histogram{},
+		bytes:     map[string]uint64{},
+	}
+}
+
+// record adds the ended request to the metrics
+func (m *Metrics) record(r *RequestStat) {
+	length := r.Length
+	if r.GZip {
+		length = r.ZLength
+	}
+	m.mutex.Lock()
+	m.requests[RequestKey{Host: r.Hostname, Code: r.Code, Method: r.Method, Engine: r.Engine}]++
+	h, ok := m.durations[r.Hostname]
+	if !ok {
+		h = &Histogram{Counts: make([]uint64, len(DurationBuckets)+1)}
+		m.durations[r.Hostname] = h
+	}
+	h.observe(r.Duration.Seconds())
+	m.bytes[r.Hostname] += uint64(length)
+	m.mutex.Unlock()
+}
+
+// Snapshot returns a copy of the metrics
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		InFlight:  atomic.LoadInt64(&m.inflight),
+		Requests:  map[RequestKey]uint64{},
+		Durations: map[string]Histogram{},
+		Bytes:     map[string]uint64{},
+	}
+	m.mutex.Lock()
+	for k, v := range m.requests {
+		s.Requests[k] = v
+	}
+	for k, h := range m.durations {
+		s.Durations[k] = Histogram{Counts: append([]uint64{}, h.Counts...), Count: h.Count, Sum: h.Sum}
+	}
+	for k, v := range m.bytes {
+		s.Bytes[k] = v
+	}
+	m.mutex.Unlock()
+	return s
+}
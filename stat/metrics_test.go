@@ -0,0 +1,32 @@
+package stat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.record(&RequestStat{RequestData: RequestData{Hostname: "developers", Code: 200, Method: "GET", Engine: "simple", Length: 1000, Duration: 3 * time.Millisecond}})
+	m.record(&RequestStat{RequestData: RequestData{Hostname: "developers", Code: 200, Method: "GET", Engine: "simple", Length: 1000, ZLength: 200, GZip: true, Duration: 2 * time.Second}})
+	m.record(&RequestStat{RequestData: RequestData{Hostname: "developers", Code: 404, Method: "GET", Duration: 20 * time.Second}})
+
+	s := m.Snapshot()
+	if s.Requests[RequestKey{Host: "developers", Code: 200, Method: "GET", Engine: "simple"}] != 2 || len(s.Requests) != 2 {
+		t.Errorf("requests: got %v", s.Requests)
+	}
+	if s.Bytes["developers"] != 1200 {
+		t.Errorf("bytes: got %d", s.Bytes["developers"])
+	}
+	h := s.Durations["developers"]
+	// 3ms is into the 0.005 bucket, 2s into the 2.5 bucket, 20s into +Inf
+	if h.Count != 3 || h.Counts[0] != 1 || h.Counts[8] != 1 || h.Counts[len(DurationBuckets)] != 1 {
+		t.Errorf("histogram: got %v", h)
+	}
+
+	// the snapshot is a copy
+	m.record(&RequestStat{RequestData: RequestData{Hostname: "developers", Code: 404, Method: "GET"}})
+	if h.Count != 3 || s.Requests[RequestKey{Host: "developers", Code: 404, Method: "GET"}] != 1 {
+		t.Error("the snapshot has been modified")
+	}
+}
@@ -0,0 +1,213 @@
+package stat
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWindow and DefaultCapacity are the window and the capacity of the recent requests when the stats section of the config does not set them
+const (
+	DefaultWindow   = 2 * time.Minute
+	DefaultCapacity = 10000
+	DefaultTop      = 10
+)
+
+// Recent keeps the requests ended during the last Window, up to Capacity requests, into a ring buffer.
+// It counts on the fly the requests by second, by page and by IP, so the rates and the top pages and IPs are always ready.
+// Adding a request costs a short lock and no scan of the requests, whatever is the load: the ring buffer is allocated once,
+// only a page or an IP not yet counted into the window allocates its entry into the counters.
+type Recent struct {
+	Window   time.Duration
+	Capacity int
+
+	mutex   sync.Mutex
+	ring    []recentEntry
+	head    int // the oldest entry
+	size    int
+	seconds []Second // ring of the seconds of the window, by unix time
+	pages   map[string]int
+	ips     map[string]int
+}
+
+type recentEntry struct {
+	request *RequestStat
+	time    time.Time
+	page    string
+	ip      string
+}
+
+// Second are the counters of the requests ended during a second
+type Second struct {
+	Time     int64 // unix time
+	Requests int
+	Errors   int // code >= 500
+	Bytes    int
+}
+
+// Rates are the rates of the requests on the window
+type Rates struct {
+	Window            time.Duration
+	Requests          int
+	Errors            int
+	Bytes             int
+	RequestsPerSecond float64
+	ErrorsPerSecond   float64
+	BytesPerSecond    float64
+	Seconds           []Second // the seconds of the window, the oldest first
+}
+
+// Count is an entry of a top
+type Count struct {
+	Key   string
+	Count int
+}
+
+// NewRecent creates a store of the recent requests
+func NewRecent(window time.Duration, capacity int) *Recent {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Recent{
+		Window:   window,
+		Capacity: capacity,
+		ring:     make([]recentEntry, capacity),
+		seconds:  make([]Second, int(window/time.Second)+1),
+		pages:    map[string]int{},
+		ips:      map[string]int{},
+	}
+}
+
+// add adds an ended request
+func (rc *Recent) add(r *RequestStat) {
+	e := recentEntry{request: r, time: r.Time, page: r.Page, ip: r.IP}
+	if e.page == "" {
+		e.page = r.Request
+	}
+	sec := e.time.Unix()
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.expire(e.time)
+	if rc.size == rc.Capacity {
+		rc.evict()
+	}
+	rc.ring[(rc.head+rc.size)%rc.Capacity] = e
+	rc.size++
+	rc.pages[e.page]++
+	rc.ips[e.ip]++
+
+	s := &rc.seconds[sec%int64(len(rc.seconds))]
+	if s.Time != sec {
+		*s = Second{Time: sec}
+	}
+	s.Requests++
+	if r.Code >= 500 {
+		s.Errors++
+	}
+	s.Bytes += r.Length
+}
+
+// expire removes the requests older than the window. The caller owns the mutex.
+func (rc *Recent) expire(now time.Time) {
+	limit := now.Add(-rc.Window)
+	for rc.size > 0 && rc.ring[rc.head].time.Before(limit) {
+		rc.evict()
+	}
+}
+
+// evict removes the oldest request. The caller owns the mutex.
+func (rc *Recent) evict() {
+	e := rc.ring[rc.head]
+	if rc.pages[e.page]--; rc.pages[e.page] <= 0 {
+		delete(rc.pages, e.page)
+	}
+	if rc.ips[e.ip]--; rc.ips[e.ip] <= 0 {
+		delete(rc.ips, e.ip)
+	}
+	rc.ring[rc.head] = recentEntry{}
+	rc.head = (rc.head + 1) % rc.Capacity
+	rc.size--
+}
+
+// Len returns the number of requests of the window
+func (rc *Recent) Len() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.expire(time.Now())
+	return rc.size
+}
+
+// Requests returns copies of the requests of the window, the oldest first
+func (rc *Recent) Requests() []*RequestStat {
+	rc.mutex.Lock()
+	rc.expire(time.Now())
+	list := make([]*RequestStat, 0, rc.size)
+	for i := 0; i < rc.size; i++ {
+		list = append(list, rc.ring[(rc.head+i)%rc.Capacity].request)
+	}
+	rc.mutex.Unlock()
+	return copyRequests(list)
+}
+
+// Rates returns the counters of the requests by second on the window, and the averages by second
+func (rc *Recent) Rates() Rates {
+	now := time.Now().Unix()
+	n := int64(len(rc.seconds) - 1)
+	rates := Rates{Window: rc.Window, Seconds: make([]Second, 0, n)}
+	rc.mutex.Lock()
+	// the actual second is not complete, it is not counted
+	for sec := now - n; sec < now; sec++ {
+		s := rc.seconds[sec%int64(len(rc.seconds))]
+		if s.Time != sec {
+			s = Second{Time: sec}
+		}
+		rates.Seconds = append(rates.Seconds, s)
+		rates.Requests += s.Requests
+		rates.Errors += s.Errors
+		rates.Bytes += s.Bytes
+	}
+	rc.mutex.Unlock()
+	if n > 0 {
+		rates.RequestsPerSecond = float64(rates.Requests) / float64(n)
+		rates.ErrorsPerSecond = float64(rates.Errors) / float64(n)
+		rates.BytesPerSecond = float64(rates.Bytes) / float64(n)
+	}
+	return rates
+}
+
+// TopPages returns the n pages with the most requests on the window (the requests without page count by their path)
+func (rc *Recent) TopPages(n int) []Count {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.expire(time.Now())
+	return top(rc.pages, n)
+}
+
+// TopIPs returns the n IPs with the most requests on the window
+func (rc *Recent) TopIPs(n int) []Count {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.expire(time.Now())
+	return top(rc.ips, n)
+}
+
+func top(counts map[string]int, n int) []Count {
+	list := make([]Count, 0, len(counts))
+	for k, c := range counts {
+		list = append(list, Count{Key: k, Count: c})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,51 @@
+package stat
+
+import (
+	"testing"
+	"time"
+)
+
+func recentRequest(page string, ip string, code int, t time.Time) *RequestStat {
+	return &RequestStat{RequestData: RequestData{Page: page, IP: ip, Code: code, Length: 10, Time: t}}
+}
+
+func TestRecent(t *testing.T) {
+	now := time.Now()
+	rc := NewRecent(10*time.Second, 5)
+
+	// out of the window: expired by the next add
+	rc.add(recentRequest("old", "10.0.0.9", 200, now.Add(-time.Minute)))
+	for i, page := range []string{"home", "home", "list", "home", "list", "error"} {
+		code := 200
+		if page == "error" {
+			code = 500
+		}
+		rc.add(recentRequest(page, "10.0.0."+string(rune('1'+i%2)), code, now.Add(-2*time.Second)))
+	}
+
+	// capacity 5: the first home has been evicted
+	if rc.Len() != 5 {
+		t.Errorf("len: got %d, want 5", rc.Len())
+	}
+	pages := rc.TopPages(2)
+	if len(pages) != 2 || pages[0] != (Count{"home", 2}) || pages[1] != (Count{"list", 2}) {
+		t.Errorf("top pages: got %v", pages)
+	}
+	ips := rc.TopIPs(0)
+	if len(ips) != 2 || ips[0].Count+ips[1].Count != 5 {
+		t.Errorf("top ips: got %v", ips)
+	}
+	requests := rc.Requests()
+	if len(requests) != 5 || requests[0].Page != "home" || requests[4].Page != "error" {
+		t.Errorf("requests: got %d", len(requests))
+	}
+
+	// the rates count every request of the window, even the evicted ones
+	rates := rc.Rates()
+	if rates.Requests != 6 || rates.Errors != 1 || rates.Bytes != 60 || len(rates.Seconds) != 10 {
+		t.Errorf("rates: got %d requests, %d errors, %d bytes, %d seconds", rates.Requests, rates.Errors, rates.Bytes, len(rates.Seconds))
+	}
+	if rates.RequestsPerSecond != 0.6 {
+		t.Errorf("rates by second: got %f", rates.RequestsPerSecond)
+	}
+}
@@ -2,7 +2,10 @@ package stat
 
 import (
 	"net"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/webability-go/xamboo/assets"
@@ -14,16 +17,34 @@ import (
 This code keeps tracks and stats of the whole webserver and served pages and requests
 */
 
+// RequestStat is the stat of a request. It is shared with the stats readers from its creation:
+// once created, it must be modified only with its Update methods, and read from other goroutines only with Copy.
 type RequestStat struct {
+	RequestData
+	mutex sync.Mutex
+}
+
+// RequestData are the data of the stat of a request
+type RequestData struct {
 	Id        uint64
+	RequestID string
 	StartTime time.Time
 	Time      time.Time
 	Hostname  string
 	Request   string
+	URI       string
+	Referer   string
+	UserAgent string
+	User      string
 	Protocol  string
 	Method    string
 	Code      int
-	Length    int
+	Length    int // bytes sent before compression
+	ZLength   int // bytes sent after compression, if the response is gziped
+	GZip      bool
+	Page      string // the page used to build the response
+	Engine    string // the engine of the page used
+	Identity  string // the version and language of the instance used
 	Duration  time.Duration
 	IP        string
 	Port      string
@@ -31,11 +52,13 @@ type RequestStat struct {
 	Context   *assets.Context `json:"-"`
 }
 
+// SiteStat are the stats of a host. They are updated at the end of each request of the host, under the mutex of the Stat.
 type SiteStat struct {
 	RequestsTotal  int            // num requests total, anything included
 	RequestsServed map[int]int    // by response code
 	LengthServed   int            // length total, anything included
-	Requests       []*RequestStat // the last minute requests
+	Requests       []*RequestStat // the requests of the window, only into the snapshots: always empty on the live stats
+	Recent         *Recent        `json:"-"` // the requests ended during the window, with the rates and the top pages and IPs
 }
 
 type Stat struct {
@@ -43,16 +66,23 @@ type Stat struct {
 	RequestsTotal  int            // num requests total, anything included
 	LengthServed   int            // length total, anything included
 	RequestsServed map[int]int    // by response code
-	Requests       []*RequestStat // by microtime. keep last minute requests
+	Requests       []*RequestStat // the alive requests then the requests of the window, only into the snapshots: always empty on the live stats
+	Recent         *Recent        `json:"-"` // the requests ended during the window, with the rates and the top pages and IPs
+	Pages          *PageStats     `json:"-"` // the latencies of the main pages, by host, page used and engine
+	Blocks         *PageStats     `json:"-"` // the latencies of the inner blocks, by host, block and engine
 
 	SitesStat map[string]*SiteStat // Every site stat. referenced by ID (from config)
 
-	mutex sync.RWMutex
+	mutex    sync.RWMutex
+	alive    sync.Map // the requests being served, by id
+	window   time.Duration
+	capacity int
 }
 
 var SystemStat *Stat
 var RequestCounter uint64
 
+// CreateStat creates the stats of the server and of each host, with the window and the capacity of the recent requests of the stats section of the config
 func CreateStat() *Stat {
 	s := &Stat{
 		Start:          time.Now(),
@@ -60,11 +90,16 @@ func CreateStat() *Stat {
 		RequestsServed: make(map[int]int),
 		LengthServed:   0,
 		SitesStat:      make(map[string]*SiteStat),
+		window:         time.Duration(config.Config.Stats.Window) * time.Second,
+		capacity:       config.Config.Stats.Capacity,
 	}
+	s.Recent = NewRecent(s.window, s.capacity)
+	s.Pages = NewPageStats()
+	s.Blocks = NewPageStats()
+	logFormats = make(map[string]assets.Log)
 	for _, host := range config.Config.Hosts {
-		s.SitesStat[host.Name] = &SiteStat{
-			RequestsServed: make(map[int]int),
-		}
+		s.SitesStat[host.Name] = s.newSiteStat()
+		logFormats[host.Name] = host.Log
 	}
 
 	// launch cleaning thread, while the xamboo go system works
@@ -77,38 +112,102 @@ func Start() {
 	SystemStat = CreateStat()
 }
 
+func (s *Stat) newSiteStat() *SiteStat {
+	return &SiteStat{
+		RequestsServed: make(map[int]int),
+		Recent:         NewRecent(s.window, s.capacity),
+	}
+}
+
+// Clean reports every minute the log lines lost by the network loggers.
+// The recent requests do not need to be cleaned anymore, they expire by themselves.
 func (s *Stat) Clean() {
-	// 1. clean Requests from stat
 	slogger := logger.GetCoreLogger("sys")
 	slogger.Println("Stats cleaner launched. Clean every minute.")
+	dropped := map[string]uint64{}
 	for {
-		n := time.Now()
-		// we keep 2 minutes
-		delta := time.Minute * 2
-		last := 0
-
-		// if it's alive: no delete
-		s.mutex.Lock()
-		for i, r := range s.Requests {
-			if r.Time.Add(delta).Before(n) {
-				last = i
-			} else {
-				break
+		// report the log lines lost by the network loggers
+		for id, n := range s.LogsDropped() {
+			if n > dropped[id] {
+				slogger.Println("Logger", id, "dropped", n-dropped[id], "lines, the sink is down or too slow")
+				dropped[id] = n
 			}
 		}
-		s.Requests = s.Requests[last:]
-		s.mutex.Unlock()
 		// we clean every 60 seconds
 		time.Sleep(time.Minute)
 	}
 }
 
+// Snapshot returns a copy of the stats and of the stats of the sites, with copies of the requests.
+// The copy can be read without lock, while the server goes on. The Recent stores are shared: they have their own locks.
+func (s *Stat) Snapshot() *Stat {
+	s.mutex.RLock()
+	c := &Stat{
+		Start:          s.Start,
+		RequestsTotal:  s.RequestsTotal,
+		LengthServed:   s.LengthServed,
+		RequestsServed: copyCodes(s.RequestsServed),
+		Recent:         s.Recent,
+		Pages:          s.Pages,
+		Blocks:         s.Blocks,
+		SitesStat:      make(map[string]*SiteStat),
+	}
+	for name, site := range s.SitesStat {
+		c.SitesStat[name] = &SiteStat{
+			RequestsTotal:  site.RequestsTotal,
+			RequestsServed: copyCodes(site.RequestsServed),
+			LengthServed:   site.LengthServed,
+			Recent:         site.Recent,
+		}
+	}
+	s.mutex.RUnlock()
+
+	// the recent requests have their own locks
+	c.Requests = append(s.AliveRequests(), s.Recent.Requests()...)
+	for _, site := range c.SitesStat {
+		site.Requests = site.Recent.Requests()
+	}
+	return c
+}
+
+// AliveRequests returns copies of the requests being served, by id
+func (s *Stat) AliveRequests() []*RequestStat {
+	list := []*RequestStat{}
+	s.alive.Range(func(key interface{}, value interface{}) bool {
+		list = append(list, value.(*RequestStat).Copy())
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list
+}
+
+func copyCodes(codes map[int]int) map[int]int {
+	c := make(map[int]int, len(codes))
+	for code, n := range codes {
+		c[code] = n
+	}
+	return c
+}
+
+func copyRequests(requests []*RequestStat) []*RequestStat {
+	c := make([]*RequestStat, len(requests))
+	for i, r := range requests {
+		c[i] = r.Copy()
+	}
+	return c
+}
+
+// LogsDropped returns the number of lines dropped by the syslog:, tcp: and udp: loggers, by logger id
+func (s *Stat) LogsDropped() map[string]uint64 {
+	return logger.Dropped()
+}
+
 func CreateRequestStat(request string, method string, protocol string, code int, length int, duration time.Duration, remoteaddr string) *RequestStat {
 
 	ip, port, _ := net.SplitHostPort(remoteaddr)
 
-	r := &RequestStat{
-		Id:        RequestCounter,
+	r := &RequestStat{RequestData: RequestData{
+		Id:        atomic.AddUint64(&RequestCounter, 1),
 		StartTime: time.Now(),
 		Time:      time.Now(),
 		Request:   request,
@@ -120,68 +219,187 @@ func CreateRequestStat(request string, method string, protocol string, code int,
 		IP:        ip,
 		Port:      port,
 		Alive:     true,
-	}
-	RequestCounter++
+	}}
 
-	SystemStat.LengthServed += length
+	atomic.AddInt64(&SystemMetrics.inflight, 1)
 
 	SystemStat.mutex.Lock()
+	SystemStat.LengthServed += length
 	SystemStat.RequestsTotal++
-	SystemStat.Requests = append(SystemStat.Requests, r)
 	SystemStat.mutex.Unlock()
+	SystemStat.alive.Store(r.Id, r)
 
-	// Adding stat to the site:
+	// the stat of the site is updated at the end, the host is still unknown
 	return r
 }
 
+// Copy returns a copy of the stat, that can be read while the request goes on
+func (r *RequestStat) Copy() *RequestStat {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return &RequestStat{RequestData: r.RequestData}
+}
+
 func (r *RequestStat) UpdateStat(code int, length int) {
+	r.mutex.Lock()
 	r.Time = time.Now()
 	if code != 0 {
 		r.Code = code
 	}
 	r.Length += length
-	SystemStat.LengthServed += length
 	r.Duration = r.Time.Sub(r.StartTime)
+	r.mutex.Unlock()
 
-	// Put the stat at the end of the pile.. it has been modified!
 	SystemStat.mutex.Lock()
-	// find the request. It is highly possible it's at the end of Pile
-	for i := len(SystemStat.Requests) - 1; i >= 0; i-- {
-		if SystemStat.Requests[i] == r {
-			if i == len(SystemStat.Requests)-1 {
-				SystemStat.Requests = SystemStat.Requests[:i]
-			} else {
-				SystemStat.Requests = append(SystemStat.Requests[:i], SystemStat.Requests[i+1:]...)
-			}
-			break
+	SystemStat.LengthServed += length
+	SystemStat.mutex.Unlock()
+}
+
+// SetRequestID sets the id of the request: the id given by the client or a proxy (X-Request-ID header) if it is valid, or a new one.
+// The new ids are unique for the server: [start time of the server]-[request counter], in base 36.
+func (r *RequestStat) SetRequestID(given string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if validRequestID(given) {
+		r.RequestID = given
+	} else {
+		r.RequestID = strconv.FormatInt(SystemStat.Start.Unix(), 36) + "-" + strconv.FormatUint(r.Id, 36)
+	}
+	return r.RequestID
+}
+
+// validRequestID accepts up to 128 letters, digits and . _ : - characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == ':' || c == '-') {
+			return false
 		}
 	}
-	SystemStat.Requests = append(SystemStat.Requests, r)
-	SystemStat.mutex.Unlock()
+	return true
 }
 
 func (r *RequestStat) UpdateProtocol(protocol string) {
+	r.mutex.Lock()
 	r.Protocol = protocol
+	r.mutex.Unlock()
+}
+
+// UpdateHostname sets the host that serves the request
+func (r *RequestStat) UpdateHostname(hostname string) {
+	r.mutex.Lock()
+	r.Hostname = hostname
+	r.mutex.Unlock()
+}
+
+// UpdateClient sets the data of the client of the request
+func (r *RequestStat) UpdateClient(uri string, referer string, useragent string, user string) {
+	r.mutex.Lock()
+	r.URI = uri
+	r.Referer = referer
+	r.UserAgent = useragent
+	r.User = user
+	r.mutex.Unlock()
+}
+
+// UpdateCode sets the code returned by the page
+func (r *RequestStat) UpdateCode(code int) {
+	r.mutex.Lock()
+	r.Code = code
+	r.mutex.Unlock()
+}
+
+// UpdateContext sets the context of the main page
+func (r *RequestStat) UpdateContext(ctx *assets.Context) {
+	r.mutex.Lock()
+	r.Context = ctx
+	r.mutex.Unlock()
+}
+
+// UpdatePage sets the page really used, its engine and the identity of its instance
+func (r *RequestStat) UpdatePage(page string, engine string, identity string) {
+	r.mutex.Lock()
+	r.Page = page
+	r.Engine = engine
+	r.Identity = identity
+	r.mutex.Unlock()
+}
+
+// UpdateCompression sets the length of the gziped response
+func (r *RequestStat) UpdateCompression(zlength int) {
+	r.mutex.Lock()
+	r.GZip = true
+	r.ZLength = zlength
+	r.mutex.Unlock()
 }
 
 func (r *RequestStat) End() {
 
-	// Call stats ? (code entry)
+	// closed case
+	r.mutex.Lock()
+	r.Alive = false
+	r.mutex.Unlock()
+	atomic.AddInt64(&SystemMetrics.inflight, -1)
+	SystemMetrics.record(r)
+
+	// stats of the request and of its site
+	SystemStat.alive.Delete(r.Id)
+	var site *SiteStat
+	SystemStat.mutex.Lock()
+	SystemStat.RequestsServed[r.Code]++
+	if r.Hostname != "" {
+		var ok bool
+		site, ok = SystemStat.SitesStat[r.Hostname]
+		if !ok {
+			site = SystemStat.newSiteStat()
+			SystemStat.SitesStat[r.Hostname] = site
+		}
+		site.RequestsTotal++
+		site.RequestsServed[r.Code]++
+		site.LengthServed += r.Length
+	}
+	SystemStat.mutex.Unlock()
+	SystemStat.Recent.add(r)
+	if site != nil {
+		site.Recent.add(r)
+	}
+	if r.Page != "" {
+		length := r.Length
+		if r.GZip {
+			length = r.ZLength
+		}
+		SystemStat.Pages.Record(PageKey{Host: r.Hostname, Page: r.Page, Engine: r.Engine}, r.Duration, r.Code >= 500, length)
+	}
+
 	// log the stat in pages and stat loggers
 	if r.Hostname == "" {
 		xlogger := logger.GetCoreLogger("errors")
 		xlogger.Println("Stat without hostname:", r.IP, r.Method, r.Protocol, r.Code, r.Request, r.Length, r.Duration)
-	} else {
-		hlogger := logger.GetHostLogger(r.Hostname, "pages")
-		slogger := logger.GetHostHook(r.Hostname, "stats")
-		if hlogger != nil {
+		return
+	}
+	hlogger := logger.WithRequestID(logger.GetHostLogger(r.Hostname, "pages"), r.RequestID)
+	if hlogger != nil {
+		if settings, ok := logFormats[r.Hostname]; ok && settings.Format != "" {
+			hlogger.Print(r.Format(settings.Format, settings.Fields))
+		} else {
 			hlogger.Println(r.IP, r.Method, r.Protocol, r.Code, r.Request, r.Length, r.Duration)
 		}
-		if slogger != nil && r.Context != nil {
-			slogger(r.Context)
-		}
 	}
 
-	// closed case
-	r.Alive = false
+	// the call: loggers of the request are called asynchronously with a copy of the stat (the response is already sent).
+	// The former hooks of the context are called synchronously: the writer and the request of the context are not valid anymore once the handler returns.
+	for _, cat := range []string{"pages", "stats"} {
+		l, function := logger.GetHostFunction(r.Hostname, cat)
+		switch f := function.(type) {
+		case func(*RequestStat):
+			rs := r.Copy()
+			l.Dispatch(func() { f(rs) })
+		case func(*assets.Context):
+			if r.Context != nil {
+				f(r.Context)
+			}
+		}
+	}
 }
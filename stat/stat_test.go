@@ -0,0 +1,144 @@
+package stat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/logger"
+)
+
+func TestSnapshot(t *testing.T) {
+	SystemStat = &Stat{
+		Start:          time.Now(),
+		RequestsServed: make(map[int]int),
+		SitesStat:      make(map[string]*SiteStat),
+		Recent:         NewRecent(0, 0),
+		Pages:          NewPageStats(),
+		Blocks:         NewPageStats(),
+	}
+	logger.Loggers = map[string]*logger.Logger{
+		"H[developers][pages]": {Logger: log.New(ioutil.Discard, "", 0)},
+	}
+	defer func() {
+		SystemStat = nil
+		logger.Loggers = nil
+	}()
+
+	done := make(chan bool)
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s := SystemStat.Snapshot()
+			for _, r := range s.Requests {
+				_ = r.Code + r.Length
+			}
+		}
+	}()
+
+	var writers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 50; j++ {
+				r := CreateRequestStat("developers/home", "GET", "HTTP/1.1", 0, 0, 0, "127.0.0.1:1234")
+				r.SetRequestID("")
+				r.UpdateHostname("developers")
+				r.UpdatePage("home", "simple", "")
+				r.UpdateStat(200, 100)
+				r.End()
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	s := SystemStat.Snapshot()
+	site := s.SitesStat["developers"]
+	if s.RequestsTotal != 400 || s.RequestsServed[200] != 400 || s.LengthServed != 40000 {
+		t.Errorf("system stat: got %d requests, %v codes, %d bytes", s.RequestsTotal, s.RequestsServed, s.LengthServed)
+	}
+	if site == nil || site.RequestsTotal != 400 || site.RequestsServed[200] != 400 || site.LengthServed != 40000 || len(site.Requests) != 400 {
+		t.Errorf("site stat: got %+v", site)
+	}
+	if site != nil && len(site.Requests) > 0 && (site.Requests[0].Alive || site.Requests[0].Page != "home") {
+		t.Errorf("site request: got %+v", site.Requests[0].RequestData)
+	}
+}
+
+func TestEndLogFormat(t *testing.T) {
+	SystemStat = &Stat{
+		RequestsServed: make(map[int]int),
+		SitesStat:      make(map[string]*SiteStat),
+		Recent:         NewRecent(0, 0),
+		Pages:          NewPageStats(),
+		Blocks:         NewPageStats(),
+	}
+	out := &bytes.Buffer{}
+	logger.Loggers = map[string]*logger.Logger{
+		"H[developers][pages]": {Logger: log.New(out, "pages: ", 0)},
+	}
+	logFormats = map[string]assets.Log{"developers": {Format: FormatJSON, Fields: []string{"page", "code"}}}
+	defer func() {
+		SystemStat = nil
+		logger.Loggers = nil
+		logFormats = nil
+	}()
+
+	r := CreateRequestStat("developers/home", "GET", "HTTP/1.1", 0, 0, 0, "127.0.0.1:1234")
+	r.SetRequestID("")
+	r.UpdateHostname("developers")
+	r.UpdatePage("home", "simple", "")
+	r.UpdateStat(200, 100)
+	r.End()
+
+	// the formatted line keeps the prefix of the logger and the request id
+	want := "pages: [" + r.RequestID + "] {\"page\":\"home\",\"code\":200}\n"
+	if out.String() != want {
+		t.Errorf("pages log: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestSetRequestID(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	SystemStat = &Stat{Start: start}
+	defer func() { SystemStat = nil }()
+
+	// [start time of the server]-[request counter] in base 36
+	generated := "t87v6t-zz"
+	for _, test := range []struct {
+		given string
+		want  string
+	}{
+		{"", generated},
+		{"abc-123", "abc-123"},
+		{"Trace.ID_1:2-3", "Trace.ID_1:2-3"},
+		{"550e8400-e29b-41d4-a716-446655440000", "550e8400-e29b-41d4-a716-446655440000"},
+		{strings.Repeat("a", 128), strings.Repeat("a", 128)},
+		{strings.Repeat("a", 129), generated},
+		{"with space", generated},
+		{"new\nline", generated},
+		{"<script>", generated},
+		{"id/with/slashes", generated},
+		{"accentué", generated},
+	} {
+		r := &RequestStat{RequestData: RequestData{Id: 1295}}
+		if got := r.SetRequestID(test.given); got != test.want || r.RequestID != test.want {
+			t.Errorf("SetRequestID(%q): got %q (stat %q), want %q", test.given, got, r.RequestID, test.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package xamboo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/engines"
+	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/utils"
+)
+
+// WarmupHost walks the pages directory of the host and loads into the caches every .page, .instance, .code, .template and .language,
+// and compiles and loads every library page.
+// If failfast is true, the warm up stops on the first error, else all the errors are returned at the end.
+func WarmupHost(host *assets.Host, failfast bool) []error {
+
+	pagesdir, _ := host.Config.GetString("pagesdir")
+	if pagesdir == "" {
+		return nil
+	}
+
+	hlogger := logger.GetHostLogger(host.Name, "sys")
+	elogger := logger.GetHostLogger(host.Name, "errors")
+	hlogger.Println("Warming up the pages of host H[" + host.Name + "] from " + pagesdir)
+
+	acceptpathparameters, _ := host.Config.GetBool("acceptpathparameters")
+	pageserver := &engines.Page{
+		PagesDir:             pagesdir,
+		AcceptPathParameters: acceptpathparameters,
+	}
+	instanceserver := &engines.Instance{
+		PagesDir: pagesdir,
+	}
+
+	// the context is only used by the engines to log and compile
+	ctx := &assets.Context{
+		LoggerError: elogger,
+		Logger:      logger.GetHostLevelLogger(host.Name),
+		Sysparams:   host.Config,
+		Plugins:     host.Plugins,
+	}
+
+	errs := []error{}
+	numpages := 0
+	walkerr := filepath.Walk(pagesdir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			if failfast {
+				return err
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		P, _ := filepath.Rel(pagesdir, path)
+		P = filepath.ToSlash(P)
+		if P == "." {
+			return nil
+		}
+		lastpath := utils.LastPath(P)
+		if !utils.FileExists(path + "/" + lastpath + ".page") {
+			return nil
+		}
+		numpages++
+
+		pagedata, err := pageserver.Load(P)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.page: %w", P, err))
+			if failfast {
+				return err
+			}
+			return nil
+		}
+		tp, _ := pagedata.GetString("type")
+		engine, ok := Engines[tp]
+		if !ok {
+			err = errors.New("Error: Server " + tp + " does not exist")
+			errs = append(errs, fmt.Errorf("%s.page: %w", P, err))
+			if failfast {
+				return err
+			}
+			return nil
+		}
+		if !engine.NeedInstance() {
+			return nil
+		}
+
+		for _, i := range warmupIdentities(path, lastpath) {
+			if _, err := instanceserver.Load(P, i); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s.instance: %w", P, i.Stringify(), err))
+				if failfast {
+					return err
+				}
+			}
+			for _, e := range []assets.Engine{engine, Engines["language"], Engines["template"]} {
+				if e == nil {
+					continue
+				}
+				instance := e.GetInstance(host.Name, pagesdir, P, i)
+				if instance == nil {
+					continue
+				}
+				if loader, ok := instance.(assets.EngineInstanceLoader); ok {
+					if err := loader.Load(ctx); err != nil {
+						errs = append(errs, fmt.Errorf("%s%s: %w", P, i.Stringify(), err))
+						if failfast {
+							return err
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if walkerr != nil && len(errs) == 0 {
+		errs = append(errs, walkerr)
+	}
+
+	for _, err := range errs {
+		elogger.Println("Warm up error:", err)
+	}
+	hlogger.Println("Warm up of host H["+host.Name+"] done:", numpages, "pages,", len(errs), "errors")
+	return errs
+}
+
+// warmupIdentities returns the identities of the files of the page found into its directory.
+// A file name is [lastpath][.version][.language].extension, so when only one part is present, it may be either a version or a language.
+func warmupIdentities(dir string, lastpath string) []assets.Identity {
+	identities := []assets.Identity{{Version: "", Language: ""}}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return identities
+	}
+	known := map[assets.Identity]bool{identities[0]: true}
+	add := func(i assets.Identity) {
+		if !known[i] {
+			known[i] = true
+			identities = append(identities, i)
+		}
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, lastpath+".") {
+			continue
+		}
+		middle := strings.TrimSuffix(name[len(lastpath):], filepath.Ext(name))
+		if middle == "" {
+			continue
+		}
+		parts := strings.Split(middle[1:], ".")
+		switch len(parts) {
+		case 1:
+			add(assets.Identity{Version: parts[0], Language: ""})
+			add(assets.Identity{Version: "", Language: parts[0]})
+		case 2:
+			add(assets.Identity{Version: parts[0], Language: parts[1]})
+		}
+	}
+	return identities
+}
@@ -0,0 +1,138 @@
+package xamboo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/webability-go/xamboo/assets"
+	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/stat"
+)
+
+// testPage writes a page with its .page and the other files of the page (name => content) into the pages directory
+func testPage(t *testing.T, pagesdir string, P string, files map[string]string) {
+	if err := os.MkdirAll(filepath.Join(pagesdir, P), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(pagesdir, P, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWarmupHost(t *testing.T) {
+	host, dir, cleanup := testHost(t, testEngine{
+		"home": func(ctx *assets.Context, e interface{}) interface{} {
+			return "home"
+		},
+	}, map[string]string{"home": ""})
+	defer cleanup()
+	host.Config.Set("pagesdir", dir)
+	testPage(t, dir, "broken1", map[string]string{"broken1.page": "type=missing\n"})
+	testPage(t, dir, "broken2", map[string]string{"broken2.page": "type=missing\n"})
+
+	errs := WarmupHost(host, false)
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), "does not exist") {
+			t.Errorf("error %d: got %v", i, err)
+		}
+	}
+
+	// failfast stops on the first broken page
+	if errs = WarmupHost(host, true); len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "broken1.page") {
+		t.Errorf("failfast: got %v", errs)
+	}
+}
+
+func TestWarmupIdentities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-warmup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testPage(t, dir, "home", map[string]string{
+		"home.page":             "",
+		"home.instance":         "",
+		"home.es.instance":      "",
+		"home.pc.fr.instance":   "",
+		"home.pc.fr.language":   "",
+		"homepage.instance":     "",
+		"other.mobile.instance": "",
+	})
+
+	want := []assets.Identity{{}, {Version: "es"}, {Language: "es"}, {Version: "pc", Language: "fr"}}
+	if got := warmupIdentities(filepath.Join(dir, "home"), "home"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	savedconfig, savedstat := config.Config, stat.SystemStat
+	config.Config = &config.ConfigDef{}
+	defer func() {
+		config.Config, stat.SystemStat = savedconfig, savedstat
+	}()
+
+	dir, err := ioutil.TempDir("", "xamboo-warmup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pagesdir := filepath.Join(dir, "pages") + "/"
+	testPage(t, pagesdir, "home", map[string]string{"home.page": "type=simple\nstatus=published\n", "home.code": "Hello"})
+	testPage(t, pagesdir, "broken", map[string]string{"broken.page": "type=missing\n"})
+	if err = ioutil.WriteFile(filepath.Join(dir, "host.conf"), []byte("pagesdir="+pagesdir+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a free port for the listener, that must not be opened
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	discard := map[string]interface{}{"sys": "discard", "errors": "discard", "pages": "discard", "stats": "discard"}
+	conf := map[string]interface{}{
+		"listeners": []map[string]interface{}{{"name": "warmup", "ip": "127.0.0.1", "port": port, "protocol": "http", "log": discard}},
+		"hosts": []map[string]interface{}{{
+			"name":      "warmup",
+			"listeners": []string{"warmup"},
+			"hostnames": []string{"localhost"},
+			"config":    []string{filepath.Join(dir, "host.conf")},
+			"log":       discard,
+		}},
+		"engines": []map[string]interface{}{},
+		"log":     discard,
+	}
+	data, _ := json.Marshal(conf)
+	file := filepath.Join(dir, "xamboo.json")
+	if err = ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Warmup(file)
+	if err == nil || !strings.Contains(err.Error(), "1 errors") {
+		t.Errorf("got %v, want 1 error", err)
+	}
+	l, err = net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal("the listener has been opened:", err)
+	}
+	l.Close()
+
+	if err = Warmup(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing config file accepted")
+	}
+}
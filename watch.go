@@ -0,0 +1,79 @@
+package xamboo
+
+import (
+	"strings"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/compiler"
+	"github.com/webability-go/xamboo/config"
+	"github.com/webability-go/xamboo/engines"
+	"github.com/webability-go/xamboo/engines/language"
+	"github.com/webability-go/xamboo/engines/simple"
+	"github.com/webability-go/xamboo/engines/template"
+	"github.com/webability-go/xamboo/logger"
+	"github.com/webability-go/xamboo/watcher"
+)
+
+// pagesCaches are the caches of the engines built from the files of the pages directories. They are all indexed by the path of the file.
+func pagesCaches() []*xcore.XCache {
+	return []*xcore.XCache{
+		engines.PageCache,
+		engines.InstanceCache,
+		simple.CodeCache,
+		template.TemplateCache,
+		language.LanguageCache,
+	}
+}
+
+// invalidatePagesCaches is the watchers handler: it removes the changed file from the caches.
+// When a whole directory changed, we do not know which files were into it, so the caches are flushed.
+// The changes of the library sources are sent to the compiler supervisor.
+func invalidatePagesCaches(path string, isdir bool) {
+	for _, c := range pagesCaches() {
+		if isdir {
+			c.Flush()
+		} else {
+			c.Del(path)
+		}
+	}
+	if !isdir && strings.HasSuffix(path, ".go") {
+		compiler.Notify(path)
+	}
+}
+
+// StartWatchers launches a file system watcher on the pages directory of each host with the watcher enabled.
+// The cache entries of the watched directories are then valid until the file changes, without any stat of the file on each hit.
+// If a directory cannot be watched, the caches of this host keep the validation of the files against their source.
+func StartWatchers() {
+	xlogger := logger.GetCoreLogger("sys")
+
+	allwatched := true
+	for _, host := range config.Config.Hosts {
+		pagesdir, _ := host.Config.GetString("pagesdir")
+		if pagesdir == "" {
+			continue
+		}
+		if !host.Watcher.Enabled {
+			allwatched = false
+			continue
+		}
+		hlogger := logger.GetHostLogger(host.Name, "sys")
+		_, err := watcher.New(pagesdir, invalidatePagesCaches, logger.GetHostLogger(host.Name, "errors"))
+		if err != nil {
+			allwatched = false
+			hlogger.Println("The pages directory " + pagesdir + " cannot be watched, the files are verified on each hit: " + err.Error())
+			continue
+		}
+		hlogger.Println("Watching the pages directory " + pagesdir)
+	}
+
+	for _, c := range pagesCaches() {
+		c.Validator = watcher.Validator
+		if allwatched {
+			// no need to expire the entries anymore, the watchers invalidate them
+			c.Expire = 0
+		}
+	}
+	xlogger.Println("File system watchers launched.")
+}
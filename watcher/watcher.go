@@ -0,0 +1,70 @@
+// watcher is the code charged to watch the pages directories of the hosts and to notify any change of file to invalidate the caches.
+// It uses inotify where it is available. On other systems New returns ErrNotSupported and the caches keep the validation of the files against their source.
+package watcher
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webability-go/xamboo/utils"
+)
+
+// ErrNotSupported is returned by New when the system or the filesystem does not support the watching of files
+var ErrNotSupported = errors.New("the file system watcher is not supported on this system")
+
+// Handler is called for every changed file (created, modified, deleted, moved) into the watched directory.
+// The path is built as the root + the relative path of the file, so it is the same as the keys of the caches of the engines.
+// isdir is true when a whole directory has been moved or deleted, or when the watcher lost some events (path is then the root).
+type Handler func(path string, isdir bool)
+
+var mutex sync.RWMutex
+var roots []string
+
+func register(root string) {
+	mutex.Lock()
+	roots = append(roots, root)
+	mutex.Unlock()
+}
+
+func unregister(root string) {
+	mutex.Lock()
+	for i, r := range roots {
+		if r == root {
+			roots = append(roots[:i], roots[i+1:]...)
+			break
+		}
+	}
+	mutex.Unlock()
+}
+
+// IsWatched returns true if the path is into a watched directory
+func IsWatched(path string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	for _, r := range roots {
+		if strings.HasPrefix(path, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator is a cache validator for files.
+// If the file is into a watched directory, the entry is valid until the watcher invalidates it, so there is no need of a stat.
+// If not, it falls back to the utils.FileValidator.
+func Validator(key string, otime time.Time) bool {
+	if IsWatched(key) {
+		return true
+	}
+	return utils.FileValidator(key, otime)
+}
+
+// normalize makes sure the root ends with a / since the pages paths are concatenated to it
+func normalize(root string) string {
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root
+}
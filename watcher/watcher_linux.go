@@ -0,0 +1,154 @@
+//go:build linux
+// +build linux
+
+package watcher
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const watchmask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// Watcher is an inotify watcher on a whole directory tree
+type Watcher struct {
+	Root    string
+	file    *os.File
+	fd      int
+	handler Handler
+	errlog  *log.Logger
+	mutex   sync.Mutex
+	watches map[int32]string // watch descriptor => relative path of the directory
+}
+
+// New creates a watcher on the directory root and all its subdirectories, and starts to listen to the changes.
+// errlog receives the errors of the watcher once it listens (it may be nil).
+func New(root string, handler Handler, errlog *log.Logger) (*Watcher, error) {
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		if err == syscall.ENOSYS {
+			return nil, ErrNotSupported
+		}
+		return nil, err
+	}
+
+	w := &Watcher{
+		Root:    normalize(root),
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		handler: handler,
+		errlog:  errlog,
+		watches: map[int32]string{},
+	}
+	if err := w.addTree(""); err != nil {
+		w.file.Close()
+		if err == syscall.EINVAL || err == syscall.ENOTSUP {
+			return nil, ErrNotSupported
+		}
+		return nil, err
+	}
+	register(w.Root)
+	go w.listen()
+	return w, nil
+}
+
+// Close stops the watcher. The caches must be validated against the files again.
+func (w *Watcher) Close() error {
+	unregister(w.Root)
+	return w.file.Close()
+}
+
+// addTree adds a watch on the directory rel and all its subdirectories
+func (w *Watcher) addTree(rel string) error {
+	return filepath.Walk(w.Root+rel, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		r, _ := filepath.Rel(w.Root, path)
+		r = filepath.ToSlash(r)
+		if r == "." {
+			r = ""
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, watchmask)
+		if err != nil {
+			return err
+		}
+		w.mutex.Lock()
+		w.watches[int32(wd)] = r
+		w.mutex.Unlock()
+		return nil
+	})
+}
+
+func (w *Watcher) path(rel string, name string) string {
+	if rel == "" {
+		return w.Root + name
+	}
+	if name == "" {
+		return w.Root + rel
+	}
+	return w.Root + rel + "/" + name
+}
+
+func (w *Watcher) listen() {
+	var buf [syscall.SizeofInotifyEvent * 4096]byte
+	for {
+		n, err := w.file.Read(buf[:])
+		if err != nil {
+			// closed
+			return
+		}
+		offset := 0
+		for offset+syscall.SizeofInotifyEvent <= n {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			name := ""
+			if event.Len > 0 {
+				bname := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				name = strings.TrimRight(string(bname), "\x00")
+			}
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// we lost events, anything may have changed
+				w.handler(w.Root, true)
+				continue
+			}
+
+			w.mutex.Lock()
+			rel, ok := w.watches[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.watches, event.Wd)
+			}
+			w.mutex.Unlock()
+			if !ok || event.Mask&syscall.IN_IGNORED != 0 {
+				continue
+			}
+
+			isdir := event.Mask&syscall.IN_ISDIR != 0
+			if isdir && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				// new directory to watch too, with anything into it
+				newrel := name
+				if rel != "" {
+					newrel = rel + "/" + name
+				}
+				if err := w.addTree(newrel); err != nil && w.errlog != nil {
+					w.errlog.Println("The directory " + w.path(newrel, "") + " cannot be watched, its changes will not be seen: " + err.Error())
+				}
+				// nothing could be cached into a new directory
+				continue
+			}
+			if event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 {
+				w.handler(w.path(rel, ""), true)
+				continue
+			}
+			w.handler(w.path(rel, name), isdir)
+		}
+	}
+}
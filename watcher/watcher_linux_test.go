@@ -0,0 +1,97 @@
+//go:build linux
+// +build linux
+
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type event struct {
+	path  string
+	isdir bool
+}
+
+// waitEvent waits for the event of the path, ignoring the other events
+func waitEvent(t *testing.T, events chan event, path string, isdir bool) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.path == path && e.isdir == isdir {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no event for %s", path)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	root, err := ioutil.TempDir("", "xamboo-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	root += "/"
+
+	events := make(chan event, 100)
+	w, err := New(root, func(path string, isdir bool) { events <- event{path, isdir} }, nil)
+	if err == ErrNotSupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if !IsWatched(root+"home/home.page") || IsWatched("/other/home.page") {
+		t.Error("watched directory")
+	}
+
+	file := root + "home.page"
+	if err = ioutil.WriteFile(file, []byte("type=simple"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, file, false)
+
+	if err = ioutil.WriteFile(file, []byte("type=library"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, file, false)
+
+	renamed := root + "main.page"
+	if err = os.Rename(file, renamed); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, file, false)
+	waitEvent(t, events, renamed, false)
+
+	// the new directories are watched too
+	dir := filepath.Join(root, "admin")
+	if err = os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the watch of the new directory is added when its event is read: wait for it before writing into it
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		w.mutex.Lock()
+		n := len(w.watches)
+		w.mutex.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the new directory is not watched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	file = root + "admin/admin.page"
+	if err = ioutil.WriteFile(file, []byte("type=simple"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, file, false)
+}
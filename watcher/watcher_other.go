@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package watcher
+
+import "log"
+
+// Watcher is not available on this system
+type Watcher struct {
+	Root string
+}
+
+// New always returns ErrNotSupported on this system
+func New(root string, handler Handler, errlog *log.Logger) (*Watcher, error) {
+	return nil, ErrNotSupported
+}
+
+// Close does nothing on this system
+func (w *Watcher) Close() error {
+	return nil
+}
@@ -0,0 +1,318 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/webability-go/xconfig"
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// The pool keeps one worker process by page (source path). The process is started on the first call,
+// and started again on the next call if it died. A new binary of the page replaces the process.
+
+// Process is a worker process running the binary of a page
+type Process struct {
+	ID       string
+	Binary   string
+	Started  time.Time
+	Restarts int
+
+	mutex     sync.Mutex
+	cmd       *exec.Cmd
+	client    *rpc.Client
+	socket    string
+	sysparams string
+	logger    *log.Logger
+	failed    time.Time // last start failure, to not restart in loop
+}
+
+// ProcessStatus is the status of a worker process
+type ProcessStatus struct {
+	ID       string
+	Binary   string
+	Running  bool
+	Pid      int
+	Started  time.Time
+	Restarts int
+}
+
+type pile struct {
+	mutex     sync.Mutex
+	processes map[string]*Process
+}
+
+var pool = &pile{
+	processes: map[string]*Process{},
+}
+
+var sockets int64
+
+// StartTimeout is the max time for a worker to open its socket
+var StartTimeout = 5 * time.Second
+
+// Get returns the process of the page. If the binary changed, the previous process is stopped and replaced.
+// The process is started on the first call to Run.
+func Get(id string, binary string, sysparams *xconfig.XConfig, logger *log.Logger) *Process {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	p, ok := pool.processes[id]
+	if ok && p.Binary == binary {
+		return p
+	}
+	if ok {
+		p.Stop()
+	}
+	marshaled := ""
+	if sysparams != nil {
+		marshaled = sysparams.Marshal()
+	}
+	p = &Process{
+		ID:        id,
+		Binary:    binary,
+		sysparams: marshaled,
+		logger:    logger,
+	}
+	pool.processes[id] = p
+	return p
+}
+
+// Restart stops the process of the page, it is started again on the next call
+func Restart(id string) error {
+	pool.mutex.Lock()
+	p, ok := pool.processes[id]
+	pool.mutex.Unlock()
+	if !ok {
+		return errors.New("Error: there is no worker for " + id)
+	}
+	p.Stop()
+	return nil
+}
+
+// StopAll stops all the worker processes
+func StopAll() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for _, p := range pool.processes {
+		p.Stop()
+	}
+}
+
+// GetStatus returns the status of all the worker processes, ordered by id
+func GetStatus() []ProcessStatus {
+	pool.mutex.Lock()
+	list := make([]ProcessStatus, 0, len(pool.processes))
+	for _, p := range pool.processes {
+		p.mutex.Lock()
+		status := ProcessStatus{
+			ID:       p.ID,
+			Binary:   p.Binary,
+			Running:  p.cmd != nil,
+			Started:  p.Started,
+			Restarts: p.Restarts,
+		}
+		if p.cmd != nil && p.cmd.Process != nil {
+			status.Pid = p.cmd.Process.Pid
+		}
+		p.mutex.Unlock()
+		list = append(list, status)
+	}
+	pool.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// start runs the binary and connects to its socket. The caller must own the lock of the process.
+func (p *Process) start() error {
+	if !p.failed.IsZero() && time.Since(p.failed) < time.Second {
+		return errors.New("Error: the worker " + p.Binary + " failed to start, retry later")
+	}
+	if !p.Started.IsZero() {
+		p.Restarts++
+	}
+	p.socket = filepath.Join(os.TempDir(), "xamboo-"+strconv.Itoa(os.Getpid())+"-"+strconv.FormatInt(atomic.AddInt64(&sockets, 1), 10)+".sock")
+	cmd := exec.Command(p.Binary)
+	cmd.Env = append(os.Environ(), SocketEnv+"="+p.socket)
+	setProcAttr(cmd)
+	if p.logger != nil {
+		cmd.Stdout = p.logger.Writer()
+		cmd.Stderr = p.logger.Writer()
+	}
+	if err := cmd.Start(); err != nil {
+		p.failed = time.Now()
+		return err
+	}
+
+	var conn net.Conn
+	var err error
+	for deadline := time.Now().Add(StartTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if conn, err = net.Dial("unix", p.socket); err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		p.failed = time.Now()
+		return fmt.Errorf("Error: the worker %s did not open its socket: %w", p.Binary, err)
+	}
+	client := rpc.NewClient(conn)
+	ok := false
+	if err := client.Call("Page.Init", p.sysparams, &ok); err != nil {
+		client.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		p.failed = time.Now()
+		return err
+	}
+
+	p.cmd = cmd
+	p.client = client
+	p.Started = time.Now()
+	p.failed = time.Time{}
+	if p.logger != nil {
+		p.logger.Println("Worker started:", p.Binary, "pid", cmd.Process.Pid)
+	}
+	go p.wait(cmd)
+	return nil
+}
+
+// wait cleans the process when it exits, it will be started again on the next call
+func (p *Process) wait(cmd *exec.Cmd) {
+	err := cmd.Wait()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.cmd != cmd {
+		// already stopped
+		return
+	}
+	if p.logger != nil {
+		p.logger.Println("Worker exited:", p.Binary, err)
+	}
+	p.client.Close()
+	p.client = nil
+	p.cmd = nil
+	os.Remove(p.socket)
+}
+
+// Stop kills the process, if it is running
+func (p *Process) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.cmd == nil {
+		return
+	}
+	p.client.Close()
+	p.cmd.Process.Kill()
+	p.client = nil
+	p.cmd = nil
+	os.Remove(p.socket)
+}
+
+// Call sends the request to the worker, starting it if needed. The call is abandoned if the context is done (the worker ends the page alone).
+func (p *Process) Call(ctx context.Context, req *Request) (*Response, error) {
+	p.mutex.Lock()
+	if p.client == nil {
+		if err := p.start(); err != nil {
+			p.mutex.Unlock()
+			return nil, err
+		}
+	}
+	client := p.client
+	p.mutex.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	resp := &Response{}
+	call := client.Go("Page.Run", req, resp, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.Error == rpc.ErrShutdown {
+		return nil, errors.New("Error: the worker " + p.Binary + " stopped during the request")
+	}
+	return resp, call.Error
+}
+
+// Run is the page function of the library engine for a page run by a worker.
+// The fields of the context are sent to the worker, and the headers, code and body it returns are put back into the context.
+func (p *Process) Run(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, params interface{}) interface{} {
+	req := &Request{
+		RequestID:      ctx.RequestID,
+		IsMainPage:     ctx.IsMainPage,
+		Code:           ctx.Code,
+		Language:       ctx.Language,
+		Version:        ctx.Version,
+		MainPage:       ctx.MainPage,
+		MainPageUsed:   ctx.MainPageUsed,
+		MainURLparams:  ctx.MainURLparams,
+		LocalPage:      ctx.LocalPage,
+		LocalPageUsed:  ctx.LocalPageUsed,
+		LocalURLparams: ctx.LocalURLparams,
+		Params:         params,
+		Template:       template,
+	}
+	if ctx.Ctx != nil {
+		req.Deadline, _ = ctx.Ctx.Deadline()
+	}
+	if ctx.Logger != nil {
+		req.LogLevel = ctx.Logger.Level
+		req.LogTags = ctx.Logger.Tags
+	}
+	if r := ctx.Request; r != nil {
+		req.Method = r.Method
+		req.URL = r.URL.String()
+		req.Proto = r.Proto
+		req.Host = r.Host
+		req.RemoteAddr = r.RemoteAddr
+		req.Header = r.Header
+		req.Form = r.Form
+		if ctx.IsMainPage && r.Body != nil {
+			// the body is given back to the request for the next pages
+			req.Body, _ = ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(bytes.NewReader(req.Body))
+		}
+	}
+	if language != nil {
+		req.Translation = &Translation{
+			Name:     language.GetName(),
+			Language: language.GetLanguage().String(),
+			Entries:  language.GetEntries(),
+		}
+	}
+
+	resp, err := p.Call(ctx.Ctx, req)
+	if err != nil {
+		ctx.Code = 500
+		return err
+	}
+	if ctx.Writer != nil {
+		for header, values := range resp.Header {
+			for _, value := range values {
+				ctx.Writer.Header().Add(header, value)
+			}
+		}
+	}
+	ctx.Code = resp.Code
+	ctx.IsGZiped = resp.IsGZiped
+	return resp.Body
+}
@@ -0,0 +1,14 @@
+//go:build linux
+// +build linux
+
+package workers
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcAttr kills the worker when the xamboo dies
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package workers
+
+import (
+	"os/exec"
+)
+
+// setProcAttr does nothing: the workers must be stopped with StopAll when the xamboo stops
+func setProcAttr(cmd *exec.Cmd) {
+}
@@ -0,0 +1,71 @@
+// workers runs the library pages as standalone processes, out of the address space of the xamboo.
+// The page is compiled with a generated main that calls Serve, and the xamboo calls it with RPC over a unix socket.
+// A worker that panics, leaks or hangs does not touch the server, and it can be restarted at any time.
+package workers
+
+import (
+	"encoding/gob"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// SocketEnv is the environment variable that gives the socket to listen on to the worker process
+const SocketEnv = "XAMBOO_WORKER_SOCKET"
+
+// Request contains the fields of the context of the page sent to the worker
+type Request struct {
+	RequestID      string
+	LogLevel       assets.LogLevel // level and tags of the leveled logger of the page
+	LogTags        string
+	Method         string
+	URL            string
+	Proto          string
+	Host           string
+	RemoteAddr     string
+	Header         http.Header
+	Form           url.Values
+	Body           []byte
+	IsMainPage     bool
+	Code           int
+	Language       string
+	Version        string
+	MainPage       string
+	MainPageUsed   string
+	MainURLparams  []string
+	LocalPage      string
+	LocalPageUsed  string
+	LocalURLparams []string
+	Params         interface{} // the entry params of an inner page, must be one of the registered types
+	Template       *xcore.XTemplate
+	Translation    *Translation
+	Deadline       time.Time // the deadline of the page into the xamboo (timeout of the page or of the request), zero if none
+}
+
+// Translation contains the language of the page (xcore.XLanguage cannot be serialized)
+type Translation struct {
+	Name     string
+	Language string
+	Entries  map[string]string
+}
+
+// Response contains the result of the page computed by the worker
+type Response struct {
+	Code     int
+	Header   http.Header
+	Body     string
+	IsGZiped bool
+}
+
+func init() {
+	// the types accepted as entry params of an inner page
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[string]string{})
+	gob.Register([]interface{}{})
+	gob.Register([]string{})
+	gob.Register(xcore.XDataset{})
+}
@@ -0,0 +1,185 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/webability-go/xconfig"
+	"github.com/webability-go/xcore/v2"
+	"golang.org/x/text/language"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+// PageFunction is the standard Run function of a library page
+type PageFunction = func(*assets.Context, *xcore.XTemplate, *xcore.XLanguage, interface{}) interface{}
+
+// Serve is the main of the worker process: it listens to the socket given by the xamboo and runs the page for each request.
+// It is called by the generated main of the worker:
+//
+//	func main() {
+//		workers.Serve(Run)
+//	}
+func Serve(run PageFunction) {
+	logger := log.New(os.Stderr, "worker: ", log.LstdFlags)
+	socket := os.Getenv(SocketEnv)
+	if socket == "" {
+		logger.Fatalln("The worker must be started by the xamboo, no", SocketEnv, "given")
+	}
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		logger.Fatalln("Error listening to the socket:", socket, err)
+	}
+	serve(listener, run, logger)
+}
+
+// ErrInnerPage is returned to a page that calls an inner page: the worker runs only its own page, not the engines of the xamboo
+var ErrInnerPage = errors.New("the inner pages cannot be called from a page run by a worker")
+
+func serve(listener net.Listener, run PageFunction, logger *log.Logger) {
+	assets.EngineWrapper = func(interface{}, string, interface{}, string, string, string) interface{} {
+		return ErrInnerPage
+	}
+	assets.EngineWrapperString = func(interface{}, string, interface{}, string, string, string) string {
+		return ErrInnerPage.Error()
+	}
+	server := rpc.NewServer()
+	server.RegisterName("Page", &Page{run: run, logger: logger})
+	server.Accept(listener)
+}
+
+// Page is the RPC service of the worker
+type Page struct {
+	run       PageFunction
+	logger    *log.Logger
+	mutex     sync.RWMutex
+	sysparams *xconfig.XConfig
+}
+
+// Init receives the config of the host (ctx.Sysparams), marshaled
+func (p *Page) Init(sysparams string, ok *bool) error {
+	c := xconfig.New()
+	if err := c.LoadString(sysparams); err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	p.sysparams = c
+	p.mutex.Unlock()
+	*ok = true
+	return nil
+}
+
+// Run builds the context of the page from the request and runs the page.
+// The context of the page ends at the deadline of the page into the xamboo, if any.
+// A panic of the page is returned as an error, the worker keeps running.
+func (p *Page) Run(req *Request, resp *Response) (err error) {
+
+	r, err := http.NewRequest(req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return err
+	}
+	r.Proto = req.Proto
+	r.Host = req.Host
+	r.RemoteAddr = req.RemoteAddr
+	r.Header = req.Header
+	r.Form = req.Form
+	w := &responseWriter{header: http.Header{}}
+
+	p.mutex.RLock()
+	sysparams := p.sysparams
+	p.mutex.RUnlock()
+
+	pagectx := context.Background()
+	if !req.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		pagectx, cancel = context.WithDeadline(pagectx, req.Deadline)
+		defer cancel()
+	}
+
+	ctx := &assets.Context{
+		Ctx:            pagectx,
+		RequestID:      req.RequestID,
+		Request:        r,
+		Writer:         w,
+		IsMainPage:     req.IsMainPage,
+		Code:           req.Code,
+		Language:       req.Language,
+		Version:        req.Version,
+		MainPage:       req.MainPage,
+		MainPageUsed:   req.MainPageUsed,
+		MainURLparams:  req.MainURLparams,
+		LocalPage:      req.LocalPage,
+		LocalPageUsed:  req.LocalPageUsed,
+		LocalURLparams: req.LocalURLparams,
+		LoggerError:    p.logger,
+		Logger:         &assets.LevelLogger{Level: req.LogLevel, Output: p.logger, ErrorOutput: p.logger, Tags: req.LogTags},
+		Sysparams:      sysparams,
+	}
+
+	var lang *xcore.XLanguage
+	if req.Translation != nil {
+		tag, _ := language.Parse(req.Translation.Language)
+		lang = xcore.NewXLanguage(req.Translation.Name, tag)
+		for entry, value := range req.Translation.Entries {
+			lang.Set(entry, value)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Println("Panic in the page:", req.LocalPage, r, string(debug.Stack()))
+			err = fmt.Errorf("panic in the page %s: %v", req.LocalPage, r)
+		}
+	}()
+
+	data := p.run(ctx, req.Template, lang, req.Params)
+
+	resp.Code = ctx.Code
+	if w.code != 0 {
+		resp.Code = w.code
+	}
+	resp.Header = w.header
+	resp.IsGZiped = ctx.IsGZiped
+	switch d := data.(type) {
+	case nil:
+		resp.Body = w.body.String()
+	case string:
+		resp.Body = w.body.String() + d
+	case error:
+		return d
+	case fmt.Stringer:
+		resp.Body = w.body.String() + d.String()
+	default:
+		return fmt.Errorf("the page returned a %T, only strings can be sent by a worker", data)
+	}
+	return nil
+}
+
+// responseWriter keeps what the page writes directly to ctx.Writer
+type responseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.code = code
+}
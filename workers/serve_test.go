@@ -0,0 +1,115 @@
+package workers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webability-go/xcore/v2"
+
+	"github.com/webability-go/xamboo/assets"
+)
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xamboo-worker-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	run := func(ctx *assets.Context, template *xcore.XTemplate, language *xcore.XLanguage, params interface{}) interface{} {
+		if ctx.Request.FormValue("panic") != "" {
+			panic("bad page")
+		}
+		if ctx.Request.FormValue("deadline") != "" {
+			deadline, ok := ctx.Ctx.Deadline()
+			return fmt.Sprint(ok, " ", deadline.Unix(), " ", ctx.Ctx.Err())
+		}
+		if ctx.Request.FormValue("inner") != "" {
+			return assets.EngineWrapperString(ctx, "other", nil, "", "", "")
+		}
+		pagesdir, _ := ctx.Sysparams.GetString("pagesdir")
+		ctx.Writer.Header().Set("X-Page", ctx.LocalPage)
+		ctx.Code = http.StatusCreated
+		return template.Execute(&xcore.XDataset{"name": language.Get("name")}) + " " + pagesdir
+	}
+	go serve(listener, run, log.New(ioutil.Discard, "", 0))
+
+	client, err := rpc.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ok := false
+	if err := client.Call("Page.Init", "pagesdir=./pages/\n", &ok); err != nil || !ok {
+		t.Fatal("Error in Init:", err)
+	}
+
+	template, _ := xcore.NewXTemplateFromString("Hello {{name}}")
+	req := &Request{
+		Method:      "GET",
+		URL:         "/home?a=1",
+		Header:      http.Header{},
+		LocalPage:   "home",
+		Code:        http.StatusOK,
+		Template:    template,
+		Translation: &Translation{Name: "home", Language: "en", Entries: map[string]string{"name": "world"}},
+	}
+	resp := &Response{}
+	if err := client.Call("Page.Run", req, resp); err != nil {
+		t.Fatal("Error in Run:", err)
+	}
+	if resp.Body != "Hello world ./pages/" || resp.Code != http.StatusCreated || resp.Header.Get("X-Page") != "home" {
+		t.Errorf("Wrong response: %#v", resp)
+	}
+
+	req.URL = "/home?panic=1"
+	if err := client.Call("Page.Run", req, &Response{}); err == nil {
+		t.Error("The panic of the page should be returned as an error")
+	}
+	// the worker is still alive after the panic
+	req.URL = "/home"
+	if err := client.Call("Page.Run", req, &Response{}); err != nil {
+		t.Error("The worker should keep running after a panic:", err)
+	}
+
+	// the context of the page has the deadline of the page into the xamboo
+	deadline := time.Now().Add(time.Minute)
+	for _, test := range []struct {
+		deadline time.Time
+		want     string
+	}{
+		{time.Time{}, "false -62135596800 <nil>"},
+		{deadline, fmt.Sprint("true ", deadline.Unix(), " <nil>")},
+		{time.Now().Add(-time.Second), "context deadline exceeded"},
+	} {
+		req.URL = "/home?deadline=1"
+		req.Deadline = test.deadline
+		resp = &Response{}
+		if err := client.Call("Page.Run", req, resp); err != nil || !strings.HasSuffix(resp.Body, test.want) {
+			t.Errorf("deadline %v: got %q and the error %v, want %q", test.deadline, resp.Body, err, test.want)
+		}
+	}
+	req.Deadline = time.Time{}
+
+	// the inner pages cannot be run into the worker
+	req.URL = "/home?inner=1"
+	resp = &Response{}
+	if err := client.Call("Page.Run", req, resp); err != nil || resp.Body != ErrInnerPage.Error() {
+		t.Errorf("inner page: got %q and the error %v", resp.Body, err)
+	}
+}